@@ -1,15 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -23,6 +26,7 @@ import (
 	"github.com/polarfoxDev/marina/internal/config"
 	"github.com/polarfoxDev/marina/internal/database"
 	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
 	"github.com/polarfoxDev/marina/internal/peer"
 	"github.com/polarfoxDev/marina/internal/version"
 )
@@ -154,6 +158,21 @@ func main() {
 				r.Get("/", handleGetSchedules(db, peerClient, nodeName))
 			})
 
+			r.Route("/instances", func(r chi.Router) {
+				r.Post("/{instanceID}/run", handleRunInstance(db, peerClient))
+				r.Post("/{instanceID}/dry-run", handleDryRunInstance(db, peerClient))
+				r.Get("/{instanceID}/phases", handleGetPhaseTotals(db, peerClient))
+			})
+
+			r.Route("/jobs", func(r chi.Router) {
+				r.Get("/{id}", handleGetJob(db, peerClient))
+				r.Post("/{id}/cancel", handleCancelJob(db, peerClient))
+			})
+
+			r.Route("/commands", func(r chi.Router) {
+				r.Get("/{id}", handleGetCommand(db, peerClient))
+			})
+
 			r.Route("/logs", func(r chi.Router) {
 				r.Get("/job/{id}", handleGetJobLogs(logger, peerClient))
 				r.Get("/system", handleGetSystemLogs(logger, peerClient, nodeName))
@@ -360,6 +379,381 @@ func handleGetJobStatus(db *database.DB, peerClient *peer.Client, nodeName strin
 	}
 }
 
+// forwardToPeer forwards a request for data of a remote node (query parameter nodeUrl) to that node and
+// writes its response. It reports whether the request was handled; mesh requests are never forwarded again.
+func forwardToPeer(w http.ResponseWriter, r *http.Request, peerClient *peer.Client, path string, body []byte) bool {
+	nodeURL := r.URL.Query().Get("nodeUrl")
+	if nodeURL == "" || r.Header.Get("X-Marina-Mesh") == "true" {
+		return false
+	}
+	if peerClient == nil {
+		http.Error(w, "Mesh mode is not configured", http.StatusBadRequest)
+		return true
+	}
+
+	resp, err := peerClient.Forward(r.Context(), r.Method, nodeURL, path, body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to forward request to peer: %v", err), http.StatusBadGateway)
+		return true
+	}
+	// Keep the peer's content type (plain text for errors from http.Error)
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(resp.Body)
+	return true
+}
+
+// runCommandWait is how long POST /api/instances/{instanceID}/run and POST /api/jobs/{id}/cancel
+// wait for the manager to process the command
+const runCommandWait = 5 * time.Second
+
+// RunInstanceRequest is the optional body of POST /api/instances/{instanceID}/run
+type RunInstanceRequest struct {
+	TargetIDs []string `json:"targetIds"` // targets to back up (all targets if empty)
+}
+
+// POST /api/instances/{instanceID}/run - Start a manual run of an instance, optionally limited to some targets
+// The manager picks up the request and reports the ID of the new job; if it does not do so within
+// runCommandWait, the command ID is returned and the job can be looked up with GET /api/commands/{id}.
+func handleRunInstance(db *database.DB, peerClient *peer.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instanceID := chi.URLParam(r, "instanceID")
+		if instanceID == "" {
+			http.Error(w, "Instance ID required", http.StatusBadRequest)
+			return
+		}
+
+		var req RunInstanceRequest
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if forwardToPeer(w, r, peerClient, "/api/instances/"+url.PathEscape(instanceID)+"/run", body) {
+			return
+		}
+
+		ctx := r.Context()
+
+		schedules, err := db.GetAllSchedules(ctx)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get schedules: %v", err), http.StatusInternalServerError)
+			return
+		}
+		index := slices.IndexFunc(schedules, func(s *model.InstanceBackupScheduleView) bool {
+			return string(s.InstanceID) == instanceID
+		})
+		if index < 0 {
+			http.Error(w, "Instance not found", http.StatusNotFound)
+			return
+		}
+		for _, targetID := range req.TargetIDs {
+			if !slices.Contains(schedules[index].TargetIDs, targetID) {
+				http.Error(w, fmt.Sprintf("Instance %s has no target %s", instanceID, targetID), http.StatusBadRequest)
+				return
+			}
+		}
+
+		commandID, err := db.AddControlCommand(ctx, model.ControlCommand{
+			Type:       model.CommandRun,
+			InstanceID: model.InstanceID(instanceID),
+			TargetIDs:  req.TargetIDs,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to request run: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Wait for the manager to pick up the command
+		response := map[string]any{"commandId": commandID, "instanceId": instanceID, "status": "pending"}
+		cmd, err := waitForCommand(ctx, db, commandID, runCommandWait)
+		if err != nil {
+			if ctx.Err() == nil {
+				http.Error(w, fmt.Sprintf("Failed to get command: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		if cmd != nil {
+			if cmd.Result != "ok" {
+				http.Error(w, fmt.Sprintf("Failed to start run: %s", cmd.Result), http.StatusConflict)
+				return
+			}
+			response["jobId"] = cmd.JobStatusID
+			response["status"] = "started"
+			if job, err := db.GetJobByID(ctx, cmd.JobStatusID); err == nil && job != nil {
+				response["status"] = job.Status
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// waitForCommand polls a command until the manager has processed it or timeout has passed.
+// Returns nil if the command is still pending.
+func waitForCommand(ctx context.Context, db *database.DB, commandID int, timeout time.Duration) (*model.ControlCommand, error) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, nil
+		case <-ticker.C:
+			cmd, err := db.GetControlCommand(ctx, commandID)
+			if err != nil {
+				return nil, err
+			}
+			if cmd != nil && cmd.ProcessedAt != nil {
+				return cmd, nil
+			}
+		}
+	}
+}
+
+// dryRunCommandWait is how long POST /api/instances/{instanceID}/dry-run waits for the report
+const dryRunCommandWait = 30 * time.Second
+
+// POST /api/instances/{instanceID}/dry-run - Report what a run of an instance would do
+// The manager builds the report without touching containers or the repository. It is returned
+// directly if ready within dryRunCommandWait (runCommandWait for mesh requests), otherwise the
+// command ID is returned and the report can be fetched with GET /api/commands/{id}.
+func handleDryRunInstance(db *database.DB, peerClient *peer.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instanceID := chi.URLParam(r, "instanceID")
+		if instanceID == "" {
+			http.Error(w, "Instance ID required", http.StatusBadRequest)
+			return
+		}
+
+		if forwardToPeer(w, r, peerClient, "/api/instances/"+url.PathEscape(instanceID)+"/dry-run", nil) {
+			return
+		}
+
+		ctx := r.Context()
+
+		schedules, err := db.GetAllSchedules(ctx)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get schedules: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !slices.ContainsFunc(schedules, func(s *model.InstanceBackupScheduleView) bool {
+			return string(s.InstanceID) == instanceID
+		}) {
+			http.Error(w, "Instance not found", http.StatusNotFound)
+			return
+		}
+
+		commandID, err := db.AddControlCommand(ctx, model.ControlCommand{
+			Type:       model.CommandDryRun,
+			InstanceID: model.InstanceID(instanceID),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to request dry run: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Requests forwarded by a peer must be answered within the peer client's timeout
+		wait := dryRunCommandWait
+		if r.Header.Get("X-Marina-Mesh") == "true" {
+			wait = runCommandWait
+		}
+		cmd, err := waitForCommand(ctx, db, commandID, wait)
+		if err != nil {
+			if ctx.Err() == nil {
+				http.Error(w, fmt.Sprintf("Failed to get command: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		if cmd == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(map[string]any{"commandId": commandID, "instanceId": instanceID, "status": "pending"})
+			return
+		}
+		if cmd.Result != "ok" {
+			http.Error(w, fmt.Sprintf("Dry run failed: %s", cmd.Result), http.StatusConflict)
+			return
+		}
+		respondJSON(w, cmd.Report)
+	}
+}
+
+// GET /api/commands/{id} - Get a command sent to the manager, including its result and job ID
+func handleGetCommand(db *database.DB, peerClient *peer.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		commandID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid command ID", http.StatusBadRequest)
+			return
+		}
+
+		if forwardToPeer(w, r, peerClient, fmt.Sprintf("/api/commands/%d", commandID), nil) {
+			return
+		}
+
+		cmd, err := db.GetControlCommand(r.Context(), commandID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get command: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if cmd == nil {
+			http.Error(w, "Command not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, cmd)
+	}
+}
+
+// GET /api/instances/{instanceID}/phases - Get the total duration of each phase for the recent jobs of an instance
+// Supports the query parameter limit (number of jobs, default: 20) and nodeUrl for instances of remote nodes.
+func handleGetPhaseTotals(db *database.DB, peerClient *peer.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		instanceID := chi.URLParam(r, "instanceID")
+		if instanceID == "" {
+			http.Error(w, "Instance ID required", http.StatusBadRequest)
+			return
+		}
+
+		limit := 20
+		if parsedLimit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+
+		if forwardToPeer(w, r, peerClient, fmt.Sprintf("/api/instances/%s/phases?limit=%d", url.PathEscape(instanceID), limit), nil) {
+			return
+		}
+
+		totals, err := db.GetPhaseTotals(r.Context(), model.InstanceID(instanceID), limit)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get phase totals: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, totals)
+	}
+}
+
+// GET /api/jobs/{id} - Get a job with the result of each of its targets and the timeline of its phases
+// Jobs of remote nodes are fetched by forwarding the request to the node given in nodeUrl.
+func handleGetJob(db *database.DB, peerClient *peer.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid job ID", http.StatusBadRequest)
+			return
+		}
+
+		if forwardToPeer(w, r, peerClient, fmt.Sprintf("/api/jobs/%d", jobID), nil) {
+			return
+		}
+
+		ctx := r.Context()
+		job, err := db.GetJobByID(ctx, jobID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get job: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if job == nil {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		targets, err := db.GetJobTargets(ctx, jobID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get job targets: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		phases, err := db.GetJobPhases(ctx, jobID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get job phases: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, model.JobDetails{JobStatus: job, Targets: targets, Phases: phases})
+	}
+}
+
+// POST /api/jobs/{id}/cancel - Cancel a running or queued job
+// The manager picks up the request and interrupts the job; a cancel the manager rejects is reported
+// with 409. If the manager does not process it within runCommandWait, the command ID is returned
+// and the outcome can be looked up with GET /api/commands/{id}.
+// Jobs of remote nodes are cancelled by forwarding the request to the node given in nodeUrl.
+func handleCancelJob(db *database.DB, peerClient *peer.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid job ID", http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+
+		if forwardToPeer(w, r, peerClient, fmt.Sprintf("/api/jobs/%d/cancel", jobID), nil) {
+			return
+		}
+
+		job, err := db.GetJobByID(ctx, jobID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get job: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if job == nil {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		if job.Status != model.StatusInProgress && job.Status != model.StatusQueued {
+			http.Error(w, fmt.Sprintf("Job is not running or queued (status: %s)", job.Status), http.StatusConflict)
+			return
+		}
+
+		commandID, err := db.AddControlCommand(ctx, model.ControlCommand{
+			Type:        model.CommandCancel,
+			JobStatusID: jobID,
+			InstanceID:  job.InstanceID,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to cancel job: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		// Wait for the manager to process the command
+		response := map[string]any{"commandId": commandID, "jobId": jobID, "status": "pending"}
+		cmd, err := waitForCommand(ctx, db, commandID, runCommandWait)
+		if err != nil {
+			if ctx.Err() == nil {
+				http.Error(w, fmt.Sprintf("Failed to get command: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+		if cmd != nil {
+			if cmd.Result != "ok" {
+				http.Error(w, fmt.Sprintf("Failed to cancel job: %s", cmd.Result), http.StatusConflict)
+				return
+			}
+			response["status"] = "cancelling"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
 // GET /api/logs/job/{id} - Get logs for a specific job status ID
 // Supports fetching logs from remote nodes via query parameter nodeUrl
 func handleGetJobLogs(logger *logging.Logger, peerClient *peer.Client) http.HandlerFunc {
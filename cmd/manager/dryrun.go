@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/docker/docker/client"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/config"
+	"github.com/polarfoxDev/marina/internal/database"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+	"github.com/polarfoxDev/marina/internal/runner"
+	"github.com/polarfoxDev/marina/internal/scheduler"
+)
+
+// dryRun prints a JSON report of what a run of an instance would do (see runner.DryRun), using the
+// config file as it is on disk, so a new config can be checked before the manager reloads it.
+// Logs go to stderr. Returns the process exit code: 0 if the run would succeed, 1 if the report
+// lists problems, 2 on errors.
+func dryRun(instanceID string) int {
+	ctx := context.Background()
+
+	cfg, err := config.Load(envDefault("CONFIG_FILE", "/config.yml"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: load config: %v\n", err)
+		return 2
+	}
+	index := slices.IndexFunc(cfg.Instances, func(inst config.BackupInstance) bool { return inst.ID == instanceID })
+	if index < 0 {
+		fmt.Fprintf(os.Stderr, "dry run: instance %s is not configured\n", instanceID)
+		return 2
+	}
+	schedules, err := scheduler.BuildSchedulesFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: build schedules from config: %v\n", err)
+		return 2
+	}
+
+	// The backend is not initialized: the repository is only read
+	nodeName, _ := resolveNodeName(cfg)
+	dest, err := newBackend(cfg, cfg.Instances[index], nodeName, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: create backend: %v\n", err)
+		return 2
+	}
+	defer dest.Close()
+
+	// The database provides the staged sizes of previous runs for the estimates
+	db, err := database.InitDB(dbPathOf(cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: init database: %v\n", err)
+		return 2
+	}
+	defer db.Close()
+	logger, err := logging.New(db.GetDB(), os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: init logger: %v\n", err)
+		return 2
+	}
+
+	dcli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: docker client: %v\n", err)
+		return 2
+	}
+	defer dcli.Close()
+
+	r := runner.New(map[model.InstanceID]backend.Backend{model.InstanceID(instanceID): dest}, dcli, logger, db, "")
+	report, err := r.DryRun(ctx, schedules, model.InstanceID(instanceID))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: %v\n", err)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "dry run: %v\n", err)
+		return 2
+	}
+	if len(report.Problems) > 0 {
+		return 1
+	}
+	return 0
+}
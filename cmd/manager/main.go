@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/docker/docker/client"
@@ -15,6 +18,7 @@ import (
 	"github.com/polarfoxDev/marina/internal/database"
 	dockerd "github.com/polarfoxDev/marina/internal/docker"
 	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/manifest"
 	"github.com/polarfoxDev/marina/internal/model"
 	"github.com/polarfoxDev/marina/internal/runner"
 	"github.com/polarfoxDev/marina/internal/scheduler"
@@ -23,6 +27,8 @@ import (
 
 func main() {
 	versionFlag := flag.Bool("version", false, "Print version and exit")
+	verifyFlag := flag.String("verify-manifest", "", "Verify a restored staging directory against its manifest and exit")
+	dryRunFlag := flag.String("dry-run", "", "Print a report of what a run of the given instance would do and exit")
 	flag.Parse()
 
 	if *versionFlag {
@@ -30,19 +36,27 @@ func main() {
 		os.Exit(0)
 	}
 
-	ctx := context.Background()
+	if *verifyFlag != "" {
+		os.Exit(verifyManifest(*verifyFlag))
+	}
+
+	if *dryRunFlag != "" {
+		os.Exit(dryRun(*dryRunFlag))
+	}
+
+	// Shut down gracefully on SIGTERM (docker stop) and SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
 	// Load configuration from config.yml
-	cfg, err := config.Load(envDefault("CONFIG_FILE", "/config.yml"))
+	configPath := envDefault("CONFIG_FILE", "/config.yml")
+	cfg, err := config.Load(configPath)
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
 
 	// Initialize unified database for both job status and logs
-	dbPath := cfg.DBPath
-	if dbPath == "" {
-		dbPath = "/var/lib/marina/marina.db"
-	}
+	dbPath := dbPathOf(cfg)
 	db, err := database.InitDB(dbPath)
 	if err != nil {
 		log.Fatalf("init database: %v", err)
@@ -68,55 +82,20 @@ func main() {
 	}
 
 	// Determine node name from config (top-level field)
-	nodeName := cfg.NodeName
-	if nodeName == "" {
-		hn, err := os.Hostname()
-		if err != nil {
-			logger.Warn("failed to get hostname: %v", err)
-			hn = "unknown"
-		}
-		nodeName = hn
+	nodeName, err := resolveNodeName(cfg)
+	if err != nil {
+		logger.Warn("failed to get hostname: %v", err)
 	}
 	logger.Info("using node name %s for backups", nodeName)
 
-	// Build map of instances from config
+	// Build map of instances from config (hostBackupPath is set after detection)
 	instances := make(map[model.InstanceID]backend.Backend)
 	for _, dest := range cfg.Instances {
-		var backendInstance backend.Backend
-		var backendErr error
-
-		// Parse restic timeout (instance-specific or global default)
-		timeoutStr := dest.ResticTimeout
-		if timeoutStr == "" {
-			timeoutStr = cfg.ResticTimeout
-		}
-		var resticTimeout time.Duration
-		if timeoutStr != "" {
-			resticTimeout, err = time.ParseDuration(timeoutStr)
-			if err != nil {
-				log.Fatalf("invalid restic timeout %q for instance %s: %v", timeoutStr, dest.ID, err)
-			}
-		}
-
-		if dest.CustomImage != "" {
-			// Use custom Docker image backend (hostBackupPath will be set after detection)
-			backendInstance, backendErr = backend.NewCustomImageBackend(dest.ID, dest.CustomImage, dest.Env, nodeName, "")
-			if backendErr != nil {
-				log.Fatalf("create custom image backend for %s: %v", dest.ID, backendErr)
-			}
-			logger.Info("loaded instance: %s -> custom image: %s", dest.ID, dest.CustomImage)
-		} else {
-			// Use Restic backend
-			backendInstance = &backend.ResticBackend{
-				ID:         dest.ID,
-				Repository: dest.Repository,
-				Env:        dest.Env,
-				Hostname:   nodeName,
-				Timeout:    resticTimeout,
-			}
-			logger.Info("loaded instance: %s -> restic: %s", dest.ID, dest.Repository)
+		backendInstance, err := newBackend(cfg, dest, nodeName, "")
+		if err != nil {
+			log.Fatalf("create backend for %s: %v", dest.ID, err)
 		}
-
+		logger.Info("loaded instance: %s -> %s", dest.ID, describeBackend(dest))
 		instances[model.InstanceID(dest.ID)] = backendInstance
 	}
 
@@ -139,12 +118,26 @@ func main() {
 	}
 	logger.Info("loaded %d backup schedules from config", len(schedules))
 
+	maxJobs, backendLimits, err := scheduler.BuildConcurrencyLimits(cfg)
+	if err != nil {
+		log.Fatalf("concurrency limits: %v", err)
+	}
+
+	shutdownTimeout, err := scheduler.BuildShutdownTimeout(cfg)
+	if err != nil {
+		log.Fatalf("shutdown timeout: %v", err)
+	}
+
 	// Create Docker client
 	dcli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		log.Fatalf("docker client: %v", err)
 	}
 
+	// Pull helper images once so backup runs don't depend on registry access
+	helperImages := make(map[string]bool)
+	prepareHelperImages(ctx, dcli, logger, schedules, helperImages)
+
 	// Detect the actual host path for /backup mount
 	hostBackupPath, err := dockerd.GetBackupHostPath(ctx, dcli)
 	if err != nil {
@@ -169,6 +162,11 @@ func main() {
 		hostBackupPath,
 	)
 
+	r.SetConcurrencyLimits(maxJobs, backendLimits)
+
+	// Remove staging data left behind by interrupted runs (no backup is running yet)
+	r.CleanupOrphanedStaging(ctx, schedules)
+
 	// Start the scheduler
 	r.Start()
 	logger.Info("scheduler started")
@@ -176,18 +174,55 @@ func main() {
 	// Schedule all configured backups
 	r.SyncBackups(schedules)
 
+	// Start runs that were still waiting in the queue when Marina stopped
+	if err := r.ResumeQueuedJobs(ctx); err != nil {
+		logger.Warn("failed to resume queued jobs: %v", err)
+	}
+
+	// Start runs that were due while Marina was down (instances with catchUp: true)
+	r.CatchUpMissedRuns(ctx)
+
+	// Execute commands from the API server (job cancellation, manual runs)
+	go r.WatchControlCommands(ctx)
+
+	// Apply changes of the config file (polled, or immediately on SIGHUP)
+	reloader := newConfigReloader(configPath, cfg, instances, helperImages, nodeName, hostBackupPath, dcli, r, logger)
+	go reloader.watch(ctx)
+
 	logger.Info("marina is running...")
 
-	// Keep running until context is cancelled
+	// Keep running until SIGTERM or SIGINT
 	<-ctx.Done()
+	stop()
+	logger.Info("shutting down, running jobs have %v to finish...", shutdownTimeout)
 
-	// Graceful stop
-	stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Graceful stop: let running jobs finish, then abort them with their normal cleanup
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 	r.Stop(stopCtx)
 	logger.Info("scheduler stopped")
 }
 
+// resolveNodeName returns the configured node name, or the hostname ("unknown" if it cannot be determined)
+func resolveNodeName(cfg *config.Config) (string, error) {
+	if cfg.NodeName != "" {
+		return cfg.NodeName, nil
+	}
+	hn, err := os.Hostname()
+	if err != nil {
+		return "unknown", err
+	}
+	return hn, nil
+}
+
+// dbPathOf returns the configured database path or the default
+func dbPathOf(cfg *config.Config) string {
+	if cfg.DBPath == "" {
+		return "/var/lib/marina/marina.db"
+	}
+	return cfg.DBPath
+}
+
 func envDefault(k, def string) string {
 	v := os.Getenv(k)
 	if v == "" {
@@ -195,3 +230,28 @@ func envDefault(k, def string) string {
 	}
 	return v
 }
+
+// verifyManifest compares a restored staging directory (the directory containing
+// marina-manifest.json) against its manifest and prints every difference.
+// Returns the process exit code: 0 if the tree matches, 1 if it differs, 2 on errors.
+func verifyManifest(dir string) int {
+	m, err := manifest.Load(filepath.Join(dir, manifest.FileName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+		return 2
+	}
+	problems, err := m.Verify(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+		return 2
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d of %d entries differ from the manifest created %s\n", len(problems), len(m.Entries), m.CreatedAt.Format(time.RFC3339))
+		return 1
+	}
+	fmt.Printf("all %d entries match the manifest created %s\n", len(m.Entries), m.CreatedAt.Format(time.RFC3339))
+	return 0
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/config"
+	dockerd "github.com/polarfoxDev/marina/internal/docker"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+	"github.com/polarfoxDev/marina/internal/runner"
+	"github.com/polarfoxDev/marina/internal/scheduler"
+)
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 10 * time.Second
+
+// newBackend creates the backend of an instance
+func newBackend(cfg *config.Config, dest config.BackupInstance, nodeName, hostBackupPath string) (backend.Backend, error) {
+	if dest.CustomImage != "" {
+		return backend.NewCustomImageBackend(dest.ID, dest.CustomImage, dest.Env, nodeName, hostBackupPath)
+	}
+
+	// Parse restic timeout (instance-specific or global default)
+	var resticTimeout time.Duration
+	if timeoutStr := resticTimeoutOf(cfg, dest); timeoutStr != "" {
+		var err error
+		resticTimeout, err = time.ParseDuration(timeoutStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid restic timeout %q: %w", timeoutStr, err)
+		}
+	}
+	return &backend.ResticBackend{
+		ID:         dest.ID,
+		Repository: dest.Repository,
+		Env:        dest.Env,
+		Hostname:   nodeName,
+		Timeout:    resticTimeout,
+	}, nil
+}
+
+// resticTimeoutOf returns the restic timeout of an instance (instance-specific or global default)
+func resticTimeoutOf(cfg *config.Config, dest config.BackupInstance) string {
+	if dest.ResticTimeout != "" {
+		return dest.ResticTimeout
+	}
+	return cfg.ResticTimeout
+}
+
+// describeBackend describes the backend of an instance for the log
+func describeBackend(dest config.BackupInstance) string {
+	if dest.CustomImage != "" {
+		return "custom image: " + dest.CustomImage
+	}
+	return "restic: " + dest.Repository
+}
+
+// backendChanged reports whether the backend of an instance has to be recreated after a reload
+func backendChanged(oldCfg, newCfg *config.Config, oldDest, newDest config.BackupInstance) bool {
+	return oldDest.Repository != newDest.Repository ||
+		oldDest.CustomImage != newDest.CustomImage ||
+		!reflect.DeepEqual(oldDest.Env, newDest.Env) ||
+		resticTimeoutOf(oldCfg, oldDest) != resticTimeoutOf(newCfg, newDest)
+}
+
+// prepareHelperImages pulls the helper images of the schedules that are not in prepared yet
+func prepareHelperImages(ctx context.Context, docker *client.Client, logger *logging.Logger, schedules []model.InstanceBackupSchedule, prepared map[string]bool) {
+	for _, schedule := range schedules {
+		helperImage := schedule.Helper.Image
+		if prepared[helperImage] {
+			continue
+		}
+		prepared[helperImage] = true
+		if err := dockerd.PrepareHelperImage(ctx, docker, helperImage); err != nil {
+			logger.Warn("helper image unavailable, volume staging will fail: %v", err)
+			continue
+		}
+		logger.Info("helper image ready: %s", helperImage)
+	}
+}
+
+// configReloader applies changes of the config file to the running scheduler
+type configReloader struct {
+	path           string
+	cfg            *config.Config
+	checksum       [sha256.Size]byte // checksum of the last config file read
+	instances      map[model.InstanceID]backend.Backend
+	helperImages   map[string]bool // helper images already prepared
+	nodeName       string
+	hostBackupPath string
+	docker         *client.Client
+	runner         *runner.Runner
+	logger         *logging.Logger
+}
+
+// newConfigReloader creates a reloader for the config (and backends) the manager was started with
+func newConfigReloader(path string, cfg *config.Config, instances map[model.InstanceID]backend.Backend, helperImages map[string]bool,
+	nodeName, hostBackupPath string, docker *client.Client, r *runner.Runner, logger *logging.Logger) *configReloader {
+	reloader := &configReloader{
+		path:           path,
+		cfg:            cfg,
+		instances:      instances,
+		helperImages:   helperImages,
+		nodeName:       nodeName,
+		hostBackupPath: hostBackupPath,
+		docker:         docker,
+		runner:         r,
+		logger:         logger,
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		reloader.checksum = sha256.Sum256(data)
+	}
+	return reloader
+}
+
+// watch reloads the config when the file changes or the process receives SIGHUP, until ctx is cancelled
+func (c *configReloader) watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			c.logger.Info("received SIGHUP, reloading config %s", c.path)
+			c.reload(ctx, true)
+		case <-ticker.C:
+			c.reload(ctx, false)
+		}
+	}
+}
+
+// reload re-reads the config file (if it changed or force is set) and applies it. An invalid config
+// is rejected with a logged error and the current one stays active.
+func (c *configReloader) reload(ctx context.Context, force bool) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		c.logger.Error("config reload failed: %v", err)
+		return
+	}
+	checksum := sha256.Sum256(data)
+	if checksum == c.checksum && !force {
+		return
+	}
+	if !force {
+		c.logger.Info("config %s changed, reloading", c.path)
+	}
+	// Remember the checksum even if the config is rejected, so an invalid file is reported once
+	c.checksum = checksum
+
+	if err := c.apply(ctx); err != nil {
+		c.logger.Error("config reload rejected, keeping the current config: %v", err)
+	}
+}
+
+// apply loads the config file and switches the runner to it
+func (c *configReloader) apply(ctx context.Context) error {
+	cfg, err := config.Load(c.path)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Instances) == 0 {
+		return errors.New("no instances configured")
+	}
+	schedules, err := scheduler.BuildSchedulesFromConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("build schedules from config: %w", err)
+	}
+	maxJobs, backendLimits, err := scheduler.BuildConcurrencyLimits(cfg)
+	if err != nil {
+		return fmt.Errorf("concurrency limits: %w", err)
+	}
+
+	// Settings that are only read at startup
+	if cfg.NodeName != c.cfg.NodeName || cfg.DBPath != c.cfg.DBPath {
+		c.logger.Warn("changes of nodeName and dbPath take effect after a restart")
+	}
+
+	old := make(map[model.InstanceID]config.BackupInstance, len(c.cfg.Instances))
+	for _, dest := range c.cfg.Instances {
+		old[model.InstanceID(dest.ID)] = dest
+	}
+
+	// Keep the backends of unchanged instances, create (and initialize) the others
+	instances := make(map[model.InstanceID]backend.Backend, len(cfg.Instances))
+	var created []backend.Backend
+	for _, dest := range cfg.Instances {
+		id := model.InstanceID(dest.ID)
+		if previous, ok := old[id]; ok && c.instances[id] != nil && !backendChanged(c.cfg, cfg, previous, dest) {
+			instances[id] = c.instances[id]
+			continue
+		}
+
+		backendInstance, err := newBackend(cfg, dest, c.nodeName, c.hostBackupPath)
+		if err == nil {
+			created = append(created, backendInstance)
+			err = backendInstance.Init(ctx)
+		}
+		if err != nil {
+			for _, b := range created {
+				_ = b.Close()
+			}
+			return fmt.Errorf("instance %s: %w", dest.ID, err)
+		}
+		instances[id] = backendInstance
+		c.logger.Info("loaded instance: %s -> %s", dest.ID, describeBackend(dest))
+	}
+
+	prepareHelperImages(ctx, c.docker, c.logger, schedules, c.helperImages)
+
+	c.runner.ReplaceBackupInstances(ctx, instances)
+	c.runner.SetConcurrencyLimits(maxJobs, backendLimits)
+	c.runner.SyncBackups(schedules)
+	c.cfg = cfg
+	c.instances = instances
+
+	c.logger.Info("config reloaded: %d instance(s), %d backup schedule(s)", len(instances), len(schedules))
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/polarfoxDev/marina/internal/config"
+)
+
+func TestBackendChanged(t *testing.T) {
+	base := config.BackupInstance{
+		ID:         "app",
+		Repository: "s3:https://s3.example.com/app",
+		Schedule:   "0 3 * * *",
+		Env:        map[string]string{"RESTIC_PASSWORD": "secret"},
+	}
+
+	tests := []struct {
+		name      string
+		oldGlobal string // global resticTimeout before the reload
+		newGlobal string // global resticTimeout after the reload
+		change    func(dest *config.BackupInstance)
+		want      bool
+	}{
+		{name: "unchanged", want: false},
+		{name: "schedule and targets do not affect the backend", change: func(d *config.BackupInstance) {
+			d.Schedule = "0 4 * * *"
+			d.Targets = []config.TargetConfig{{Volume: "data"}}
+			d.Retention = "7d:4w:6m"
+		}, want: false},
+		{name: "repository", change: func(d *config.BackupInstance) { d.Repository = "/mnt/restic" }, want: true},
+		{name: "custom image", change: func(d *config.BackupInstance) { d.CustomImage = "backup:latest" }, want: true},
+		{name: "env value", change: func(d *config.BackupInstance) { d.Env = map[string]string{"RESTIC_PASSWORD": "other"} }, want: true},
+		{name: "env added", change: func(d *config.BackupInstance) {
+			d.Env = map[string]string{"RESTIC_PASSWORD": "secret", "AWS_ACCESS_KEY_ID": "key"}
+		}, want: true},
+		{name: "instance restic timeout", change: func(d *config.BackupInstance) { d.ResticTimeout = "2h" }, want: true},
+		{name: "global restic timeout", oldGlobal: "1h", newGlobal: "2h", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldDest := base
+			newDest := base
+			newDest.Env = map[string]string{"RESTIC_PASSWORD": "secret"}
+			if tt.change != nil {
+				tt.change(&newDest)
+			}
+			oldCfg := &config.Config{ResticTimeout: tt.oldGlobal}
+			newCfg := &config.Config{ResticTimeout: tt.newGlobal}
+			if got := backendChanged(oldCfg, newCfg, oldDest, newDest); got != tt.want {
+				t.Errorf("backendChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackendChanged_InstanceTimeoutShadowsGlobal(t *testing.T) {
+	dest := config.BackupInstance{ID: "app", Repository: "/mnt/restic", ResticTimeout: "30m"}
+	if backendChanged(&config.Config{ResticTimeout: "1h"}, &config.Config{ResticTimeout: "2h"}, dest, dest) {
+		t.Error("a global resticTimeout change recreated a backend with its own timeout")
+	}
+}
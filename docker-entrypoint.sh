@@ -16,7 +16,14 @@ shutdown() {
     exit 0
 }
 
+# Forward SIGHUP to the manager, which reloads the config
+reload() {
+    echo "Reloading Marina configuration..."
+    kill -HUP "$manager_pid" 2>/dev/null || true
+}
+
 trap shutdown TERM INT
+trap reload HUP
 
 # Start the backup manager in the background
 echo "Starting Marina backup manager..."
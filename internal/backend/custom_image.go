@@ -172,10 +172,12 @@ func (b *CustomImageBackend) Backup(ctx context.Context, paths []string, tags []
 	}
 	containerID := resp.ID
 
-	// Ensure cleanup on error
+	// Ensure cleanup on error and cancellation (the container must not outlive a cancelled job)
 	defer func() {
+		cleanupCtx := context.WithoutCancel(ctx)
 		timeout := 2
-		_ = b.dockerClient.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
+		_ = b.dockerClient.ContainerStop(cleanupCtx, containerID, container.StopOptions{Timeout: &timeout})
+		_ = b.dockerClient.ContainerRemove(cleanupCtx, containerID, container.RemoveOptions{Force: true})
 	}()
 
 	// Start the container first
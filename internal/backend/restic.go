@@ -1,12 +1,23 @@
 package backend
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+
+	"github.com/polarfoxDev/marina/internal/docker"
 )
 
 // ResticBackend implements the Backend interface using Restic
@@ -27,29 +38,40 @@ func (instance *ResticBackend) GetImage() string {
 }
 
 func (instance *ResticBackend) GetResticTimeout() string {
-	timeout := instance.Timeout
-	if timeout == 0 {
-		timeout = 60 * time.Minute
+	return instance.timeout().String()
+}
+
+// timeout returns the configured timeout for restic operations (default 60 minutes)
+func (instance *ResticBackend) timeout() time.Duration {
+	if instance.Timeout == 0 {
+		return 60 * time.Minute
 	}
-	return timeout.String()
+	return instance.Timeout
 }
 
 func (instance *ResticBackend) Close() error { return nil }
 
 func (instance *ResticBackend) runRestic(ctx context.Context, args ...string) (string, error) {
-	// Determine timeout (use configured timeout or default to 60 minutes)
-	timeout := instance.Timeout
-	if timeout == 0 {
-		timeout = 60 * time.Minute
-	}
+	return instance.runResticWithStdin(ctx, nil, args...)
+}
 
+// resticWaitDelay bounds how long Wait keeps copying output after restic was killed
+// (e.g. when a child process still holds its stdout open)
+var resticWaitDelay = 5 * time.Second
+
+// runResticWithStdin runs restic with the given reader as stdin (nil means /dev/null).
+// The reader is copied from a goroutine that the call does not wait for, so a reader that blocks
+// (e.g. a stalled dump stream) cannot keep a cancelled run from returning; the goroutine ends
+// once the caller closes the reader.
+func (instance *ResticBackend) runResticWithStdin(ctx context.Context, stdin io.Reader, args ...string) (string, error) {
 	// Create a timeout context to prevent infinite hangs
-	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, instance.timeout())
 	defer cancel()
 
 	// Prepend global flags to all restic commands
 	fullArgs := append([]string{"--cleanup-cache"}, args...)
 	cmd := exec.CommandContext(timeoutCtx, "restic", fullArgs...)
+	cmd.WaitDelay = resticWaitDelay
 	// Set repository and cleanup-cache flag to handle corrupted cache
 	cmd.Env = append(os.Environ(), "RESTIC_REPOSITORY="+instance.Repository)
 	// Add custom environment variables
@@ -57,57 +79,58 @@ func (instance *ResticBackend) runRestic(ctx context.Context, args ...string) (s
 		cmd.Env = append(cmd.Env, k+"="+v)
 	}
 
-	// Use pipes to avoid buffer deadlock issues
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("create stdout pipe: %w", err)
+	// Collect output in buffers; Wait only returns once all output has been copied
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	var stdinPipe io.WriteCloser
+	if stdin != nil {
+		pipe, err := cmd.StdinPipe()
+		if err != nil {
+			return "", fmt.Errorf("restic stdin: %w", err)
+		}
+		stdinPipe = pipe
+	} else {
+		// Open /dev/null and set it as stdin to prevent restic from trying to read input
+		devNull, err := os.Open("/dev/null")
+		if err != nil {
+			return "", fmt.Errorf("open /dev/null: %w", err)
+		}
+		defer devNull.Close()
+		cmd.Stdin = devNull
 	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("create stderr pipe: %w", err)
-	}
-	// Open /dev/null and set it as stdin to prevent restic from trying to read input
-	devNull, err := os.Open("/dev/null")
-	if err != nil {
-		return "", fmt.Errorf("open /dev/null: %w", err)
-	}
-	defer devNull.Close()
-	cmd.Stdin = devNull
 
-	// Start the command
 	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("start restic: %w", err)
+		return "", fmt.Errorf("restic %v failed: %w", args, err)
+	}
+	copyErr := make(chan error, 1)
+	if stdinPipe != nil {
+		go func() {
+			_, err := io.Copy(stdinPipe, stdin)
+			copyErr <- err
+			_ = stdinPipe.Close()
+		}()
 	}
 
-	// Read output in separate goroutines to prevent blocking
-	stdoutChan := make(chan string, 1)
-	stderrChan := make(chan string, 1)
-
-	go func() {
-		data, _ := io.ReadAll(stdoutPipe)
-		stdoutChan <- string(data)
-	}()
-
-	go func() {
-		data, _ := io.ReadAll(stderrPipe)
-		stderrChan <- string(data)
-	}()
-
-	// Wait for command to complete
-	cmdErr := cmd.Wait()
-
-	// Collect output
-	stdout := <-stdoutChan
-	stderr := <-stderrChan
-
-	if cmdErr != nil {
-		return "", fmt.Errorf("restic %v failed: %w\nstderr: %s\nstdout: %s", args, cmdErr, stderr, stdout)
+	err := cmd.Wait()
+	if err == nil {
+		// restic read its input to the end, so the copy has finished; report a failing reader
+		select {
+		case err = <-copyErr:
+			if err != nil {
+				err = fmt.Errorf("read stdin: %w", err)
+			}
+		default:
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("restic %v failed: %w\nstderr: %s\nstdout: %s", args, err, stderr.String(), stdout.String())
 	}
 
 	// Return combined output for logging
-	combined := stdout
-	if stderr != "" {
-		combined += "\nstderr: " + stderr
+	combined := stdout.String()
+	if stderr.Len() > 0 {
+		combined += "\nstderr: " + stderr.String()
 	}
 	return combined, nil
 }
@@ -133,6 +156,37 @@ func (instance *ResticBackend) Backup(ctx context.Context, paths []string, tags
 		// The actual backup will fail if there's a real locking issue
 	}
 
+	return instance.runRestic(ctx, instance.backupArgs(paths, tags)...)
+}
+
+// BackupStdin stores everything read from stdin as a single file in a new snapshot
+// ('restic backup --stdin'). The caller is responsible for closing stdin when the data ends.
+func (instance *ResticBackend) BackupStdin(ctx context.Context, stdin io.Reader, filename string, tags []string) (string, error) {
+	// Clear stale locks first, same as Backup
+	_, _ = instance.runRestic(ctx, "unlock")
+
+	return instance.runResticWithStdin(ctx, stdin, instance.stdinBackupArgs(filename, tags)...)
+}
+
+// ForgetSnapshot removes a single snapshot, e.g. one holding an incomplete stdin backup
+func (instance *ResticBackend) ForgetSnapshot(ctx context.Context, snapshotID string) (string, error) {
+	return instance.runRestic(ctx, "forget", snapshotID)
+}
+
+// snapshotSavedPattern matches the "snapshot <id> saved" line printed by 'restic backup'
+var snapshotSavedPattern = regexp.MustCompile(`snapshot ([0-9a-f]+) saved`)
+
+// SnapshotIDFromOutput extracts the ID of the snapshot created by 'restic backup' from its output.
+// Returns "" if no snapshot was saved.
+func SnapshotIDFromOutput(output string) string {
+	if m := snapshotSavedPattern.FindStringSubmatch(output); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// backupArgs builds the arguments for a 'restic backup' call
+func (instance *ResticBackend) backupArgs(paths []string, tags []string) []string {
 	args := []string{"backup", "--verbose"}
 	// Set hostname if configured
 	if instance.Hostname != "" {
@@ -142,12 +196,162 @@ func (instance *ResticBackend) Backup(ctx context.Context, paths []string, tags
 	for _, t := range tags {
 		args = append(args, "--tag", t)
 	}
+	return args
+}
+
+// stdinBackupArgs builds the arguments for a 'restic backup --stdin' call
+func (instance *ResticBackend) stdinBackupArgs(filename string, tags []string) []string {
+	args := []string{"backup", "--verbose", "--stdin", "--stdin-filename", filename}
+	if instance.Hostname != "" {
+		args = append(args, "--host", instance.Hostname)
+	}
+	for _, t := range tags {
+		args = append(args, "--tag", t)
+	}
+	return args
+}
 
-	return instance.runRestic(ctx, args...)
+// BackupCommand returns the restic command line Backup runs for the given paths and tags
+func (instance *ResticBackend) BackupCommand(paths []string, tags []string) []string {
+	return append([]string{"restic"}, instance.backupArgs(paths, tags)...)
+}
+
+// BackupStdinCommand returns the restic command line BackupStdin runs
+func (instance *ResticBackend) BackupStdinCommand(filename string, tags []string) []string {
+	return append([]string{"restic"}, instance.stdinBackupArgs(filename, tags)...)
+}
+
+// DirectVolume is a Docker volume that restic reads in place instead of from staging
+type DirectVolume struct {
+	Name      string // Docker volume name
+	MountPath string // stable mount path inside the restic helper container
+}
+
+// directSecretsFile is where BackupDirect puts the repository and its credentials inside the helper container
+const directSecretsFile = "/tmp/marina-restic.env"
+
+// directCacheDir is restic's cache directory in the direct backup helper unless RESTIC_CACHE_DIR
+// is set. It lies in Marina's data volume, which the helper shares, so the cache survives between runs.
+const directCacheDir = "/var/lib/marina/restic-cache"
+
+// directForwardedEnv are the prefixes of the variables in Marina's environment that restic and its
+// storage backends read; BackupDirect passes them on to the helper container
+var directForwardedEnv = []string{
+	"RESTIC_", "AWS_", "B2_", "AZURE_", "GOOGLE_", "OS_", "ST_", "RCLONE_",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy",
+	"SSL_CERT_FILE", "SSL_CERT_DIR",
+}
+
+// BackupDirect performs the backup from a helper container so that volumes can be mounted
+// read-only at stable paths and read in place. The helper runs Marina's own image and shares
+// Marina's mounts (staging directory, local repositories, SSH keys) and network namespace,
+// so staged paths and the repository are reachable as in a regular Backup.
+// The helper starts with the image's env only. The repository, the restic and storage backend
+// variables of Marina's environment (directForwardedEnv) and the instance's env, which takes
+// precedence, are not set in the container's Env, which 'docker inspect' shows, but copied in as
+// a file that is sourced and deleted before restic starts.
+func (instance *ResticBackend) BackupDirect(ctx context.Context, cli *client.Client, volumes []DirectVolume, paths []string, tags []string) (string, error) {
+	// Clear stale locks first, same as Backup
+	_, _ = instance.runRestic(ctx, "unlock")
+
+	self, err := docker.InspectSelf(ctx, cli)
+	if err != nil {
+		return "", fmt.Errorf("direct backup: %w", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, instance.timeout())
+	defer cancel()
+
+	imageInfo, err := cli.ImageInspect(ctx, self.Image)
+	if err != nil {
+		return "", fmt.Errorf("direct backup: inspect image: %w", err)
+	}
+	var env []string
+	if imageInfo.Config != nil {
+		env = imageInfo.Config.Env
+	}
+
+	args := append([]string{"--cleanup-cache"}, instance.backupArgs(paths, tags)...)
+
+	mounts := make([]mount.Mount, 0, len(volumes))
+	for _, v := range volumes {
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeVolume,
+			Source:   v.Name,
+			Target:   v.MountPath,
+			ReadOnly: true,
+		})
+	}
+
+	config := &container.Config{
+		Image: self.Image,
+		Entrypoint: []string{"/bin/sh", "-c",
+			fmt.Sprintf(`set -a && . %[1]s && set +a && rm -f %[1]s && exec restic "$@"`, directSecretsFile), "restic"},
+		Cmd: args,
+		Env: env,
+	}
+	hostConfig := &container.HostConfig{
+		VolumesFrom: []string{self.ID},
+		NetworkMode: container.NetworkMode("container:" + self.ID),
+		Mounts:      mounts,
+	}
+
+	containerName := fmt.Sprintf("marina-restic-%s-%d", instance.ID, time.Now().UnixNano())
+	secrets := map[string][]byte{directSecretsFile: instance.secretsFile(os.Environ())}
+	output, exitCode, err := docker.RunToCompletionWithFiles(timeoutCtx, cli, containerName, config, hostConfig, secrets)
+	if err != nil {
+		return "", fmt.Errorf("restic %v: %w", args, err)
+	}
+	if exitCode != 0 {
+		return "", fmt.Errorf("restic %v failed with exit code %d\noutput: %s", args, exitCode, output)
+	}
+	return output, nil
+}
+
+// secretsFile renders the environment of the direct backup helper as shell assignments: the
+// forwarded variables of environ, overridden by the instance's env and RESTIC_REPOSITORY.
+// RESTIC_CACHE_DIR defaults to directCacheDir.
+func (instance *ResticBackend) secretsFile(environ []string) []byte {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !slices.ContainsFunc(directForwardedEnv, func(prefix string) bool { return strings.HasPrefix(key, prefix) }) {
+			continue
+		}
+		env[key] = value
+	}
+	maps.Copy(env, instance.Env)
+	env["RESTIC_REPOSITORY"] = instance.Repository
+	if env["RESTIC_CACHE_DIR"] == "" {
+		env["RESTIC_CACHE_DIR"] = directCacheDir
+	}
+
+	var b bytes.Buffer
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		// Single-quoted, so values are taken literally; embedded quotes are closed, escaped and reopened
+		fmt.Fprintf(&b, "%s='%s'\n", key, strings.ReplaceAll(env[key], "'", `'\''`))
+	}
+	return b.Bytes()
 }
 
 func (instance *ResticBackend) DeleteOldSnapshots(ctx context.Context, daily, weekly, monthly int) (string, error) {
-	args := []string{"forget", "--prune"}
+	return instance.runRestic(ctx, forgetArgs("--prune", daily, weekly, monthly)...)
+}
+
+// RetentionCommand returns the restic command line DeleteOldSnapshots runs
+func (instance *ResticBackend) RetentionCommand(daily, weekly, monthly int) []string {
+	return append([]string{"restic"}, forgetArgs("--prune", daily, weekly, monthly)...)
+}
+
+// PreviewRetention lists the snapshots DeleteOldSnapshots would keep and remove
+// ('restic forget --dry-run') without changing the repository, not even by locking it
+func (instance *ResticBackend) PreviewRetention(ctx context.Context, daily, weekly, monthly int) (string, error) {
+	return instance.runRestic(ctx, append(forgetArgs("--dry-run", daily, weekly, monthly), "--no-lock")...)
+}
+
+// forgetArgs builds the arguments for a 'restic forget' call with the given mode flag and keep policy
+func forgetArgs(mode string, daily, weekly, monthly int) []string {
+	args := []string{"forget", mode}
 	if daily > 0 {
 		args = append(args, "--keep-daily", fmt.Sprint(daily))
 	}
@@ -157,5 +361,5 @@ func (instance *ResticBackend) DeleteOldSnapshots(ctx context.Context, daily, we
 	if monthly > 0 {
 		args = append(args, "--keep-monthly", fmt.Sprint(monthly))
 	}
-	return instance.runRestic(ctx, args...)
+	return args
 }
@@ -2,10 +2,13 @@ package backend
 
 import (
 	"context"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // createFakeRestic writes a fake 'restic' executable into a temp dir and
@@ -19,6 +22,11 @@ if [ "$1" = "--cleanup-cache" ]; then
   shift
 fi
 
+if [ "$1" = "forget" ] && [ "$2" = "--dry-run" ]; then
+  echo "ARGS:$@"
+  echo "keep 2 snapshots"
+  exit 0
+fi
 if [ "$1" = "forget" ]; then
   echo "forget"
   echo "--prune"
@@ -34,6 +42,12 @@ if [ "$1" = "init" ]; then
   echo "init OK"
   exit 0
 fi
+if [ "$1" = "backup" ] && [ "$3" = "--stdin" ]; then
+  echo "ARGS:$@"
+  echo "STDIN:$(cat)"
+  echo "snapshot 1a2b3c4d saved"
+  exit 0
+fi
 echo "REPO=$RESTIC_REPOSITORY"
 echo "PASS=$RESTIC_PASSWORD"
 echo "CUSTOM=$CUSTOM"
@@ -79,3 +93,164 @@ func TestBackupAndRetentionBuildArgsAndEnv(t *testing.T) {
 		}
 	}
 }
+
+func TestBackupStdin(t *testing.T) {
+	createFakeRestic(t)
+	b := &ResticBackend{ID: "test", Repository: "/repo/location", Hostname: "node1"}
+	out, err := b.BackupStdin(context.Background(), strings.NewReader("CREATE TABLE t;"), "db/postgres/dump.sql", []string{"db:postgres"})
+	if err != nil {
+		t.Fatalf("BackupStdin error: %v", err)
+	}
+	if !strings.Contains(out, "ARGS:backup --verbose --stdin --stdin-filename db/postgres/dump.sql --host node1 --tag db:postgres") {
+		t.Fatalf("arguments not built correctly; output: %s", out)
+	}
+	if !strings.Contains(out, "STDIN:CREATE TABLE t;") {
+		t.Fatalf("stdin not passed to restic; output: %s", out)
+	}
+	if id := SnapshotIDFromOutput(out); id != "1a2b3c4d" {
+		t.Fatalf("expected snapshot ID 1a2b3c4d, got %q", id)
+	}
+}
+
+func TestSnapshotIDFromOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"saved", "Files: 1 new\nsnapshot 9f8e7d6c saved\n", "9f8e7d6c"},
+		{"not saved", "Fatal: unable to open repository", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SnapshotIDFromOutput(tt.output); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreviewRetention(t *testing.T) {
+	createFakeRestic(t)
+	b := &ResticBackend{ID: "test", Repository: "/repo/location"}
+	out, err := b.PreviewRetention(context.Background(), 7, 0, 6)
+	if err != nil {
+		t.Fatalf("PreviewRetention error: %v", err)
+	}
+	if !strings.Contains(out, "ARGS:forget --dry-run --keep-daily 7 --keep-monthly 6 --no-lock") {
+		t.Fatalf("arguments not built correctly; output: %s", out)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	b := &ResticBackend{ID: "test", Repository: "/repo/location", Hostname: "node1"}
+	tests := []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{"backup", b.BackupCommand([]string{"/backup/a", "/backup/b"}, []string{"volume:a"}), "restic backup --verbose --host node1 /backup/a /backup/b --tag volume:a"},
+		{"stdin", b.BackupStdinCommand("db/pg/dump.sql", []string{"db:pg"}), "restic backup --verbose --stdin --stdin-filename db/pg/dump.sql --host node1 --tag db:pg"},
+		{"retention", b.RetentionCommand(7, 4, 0), "restic forget --prune --keep-daily 7 --keep-weekly 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(tt.got, " "); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSecretsFile(t *testing.T) {
+	b := &ResticBackend{ID: "test", Repository: "s3:https://s3.example.com/bucket", Env: map[string]string{
+		"RESTIC_PASSWORD":       `it's $HOME "quoted"`,
+		"AWS_SECRET_ACCESS_KEY": "a b\\c`d`",
+	}}
+	environ := []string{
+		"AWS_SECRET_ACCESS_KEY=from-marina",
+		"AWS_DEFAULT_REGION=eu-central-1",
+		"HTTPS_PROXY=http://proxy:3128",
+		"RESTIC_REPOSITORY=/other/repo",
+		"HOME=/root",
+		"API_TOKEN=not-for-restic",
+	}
+
+	tests := []struct {
+		name    string
+		environ []string
+		print   []string
+		want    []string
+	}{
+		{
+			name:  "instance env only",
+			print: []string{"RESTIC_REPOSITORY", "RESTIC_PASSWORD", "AWS_SECRET_ACCESS_KEY", "RESTIC_CACHE_DIR"},
+			want:  []string{"s3:https://s3.example.com/bucket", `it's $HOME "quoted"`, "a b\\c`d`", directCacheDir},
+		},
+		{
+			name:    "forwards restic and backend variables of Marina's env",
+			environ: environ,
+			print:   []string{"RESTIC_REPOSITORY", "AWS_SECRET_ACCESS_KEY", "AWS_DEFAULT_REGION", "HTTPS_PROXY", "HOME", "API_TOKEN"},
+			want:    []string{"s3:https://s3.example.com/bucket", "a b\\c`d`", "eu-central-1", "http://proxy:3128", "unset", "unset"},
+		},
+		{
+			name:    "keeps a configured cache dir",
+			environ: []string{"RESTIC_CACHE_DIR=/cache/restic"},
+			print:   []string{"RESTIC_CACHE_DIR"},
+			want:    []string{"/cache/restic"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "restic.env")
+			if err := os.WriteFile(path, b.secretsFile(tt.environ), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			// Source the file the way the direct backup helper does (in an empty env) and print the values back
+			script := `set -a && . "$1" && set +a && shift && for v in "$@"; do eval "printf '%s\n' \"\${$v-unset}\""; done`
+			cmd := exec.Command("/bin/sh", append([]string{"-c", script, "sh", path}, tt.print...)...)
+			cmd.Env = []string{}
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("sourcing secrets file failed: %v", err)
+			}
+			want := strings.Join(tt.want, "\n") + "\n"
+			if string(out) != want {
+				t.Errorf("expected %q, got %q", want, out)
+			}
+		})
+	}
+}
+
+// TestBackupStdin_CancelWithBlockedReader checks that cancelling a stdin backup returns even if
+// the reader never delivers data or EOF
+func TestBackupStdin_CancelWithBlockedReader(t *testing.T) {
+	createFakeRestic(t)
+	oldDelay := resticWaitDelay
+	resticWaitDelay = 100 * time.Millisecond
+	t.Cleanup(func() { resticWaitDelay = oldDelay })
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &ResticBackend{ID: "test", Repository: "/repo/location"}
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.BackupStdin(ctx, pr, "db/app/dump.sql", nil)
+		done <- err
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error after cancelling the backup")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("BackupStdin did not return after the context was cancelled")
+	}
+}
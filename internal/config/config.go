@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/polarfoxDev/marina/internal/helpers"
 )
 
 // Config represents the complete configuration file
@@ -20,6 +25,27 @@ type Config struct {
 	NodeName      string           `yaml:"nodeName,omitempty"`      // Optional custom node name (defaults to hostname)
 	AuthPassword  string           `yaml:"authPassword,omitempty"`  // Optional authentication password for API access
 	Peers         []string         `yaml:"peers,omitempty"`         // Optional peer API URLs for federation (e.g., "http://marina-node2:8080")
+	Helper        HelperConfig     `yaml:"helper,omitempty"`        // Global default settings for helper containers
+	Timezone      string           `yaml:"timezone,omitempty"`      // Global default IANA time zone for schedules (default: container local time)
+	Jitter        string           `yaml:"jitter,omitempty"`        // Global default maximum random delay of scheduled runs (e.g., "5m")
+	Blackouts     []BlackoutConfig `yaml:"blackouts,omitempty"`     // Windows in which scheduled runs of all instances do not start
+
+	JobTimeout     string `yaml:"jobTimeout,omitempty"`     // Global default limit of a whole run (default: "12h")
+	HookTimeout    string `yaml:"hookTimeout,omitempty"`    // Global default limit of each pre- and post-hook (default: none)
+	DumpTimeout    string `yaml:"dumpTimeout,omitempty"`    // Global default limit of each database dump (default: none)
+	StagingTimeout string `yaml:"stagingTimeout,omitempty"` // Global default limit of staging each volume (default: none)
+
+	ShutdownTimeout string `yaml:"shutdownTimeout,omitempty"` // How long running jobs may finish on shutdown before they are aborted (default: "5m")
+
+	MaxConcurrentJobs           int            `yaml:"maxConcurrentJobs,omitempty"`           // Maximum number of jobs running at the same time (default: unlimited)
+	MaxConcurrentJobsPerBackend map[string]int `yaml:"maxConcurrentJobsPerBackend,omitempty"` // Maximum per backend type: restic, custom (default: unlimited)
+}
+
+// HelperConfig configures the short-lived helper containers that copy and measure volume data
+type HelperConfig struct {
+	Image  string  `yaml:"image,omitempty"`  // Helper image (default: "alpine:3.20"); must provide sh, cp and du (and rsync for incremental staging, else Marina's image is used)
+	CPUs   float64 `yaml:"cpus,omitempty"`   // Optional CPU limit (e.g., 0.5)
+	Memory string  `yaml:"memory,omitempty"` // Optional memory limit (e.g., "256m", "1g")
 }
 
 // BackupInstance represents a backup instance configuration
@@ -27,11 +53,42 @@ type BackupInstance struct {
 	ID            string            `yaml:"id"`
 	Repository    string            `yaml:"repository,omitempty"`    // Restic repository (not used if customImage is set)
 	CustomImage   string            `yaml:"customImage,omitempty"`   // Custom Docker image for backup (alternative to Restic)
-	Schedule      string            `yaml:"schedule"`                // Cron schedule for this instance's backups
+	Schedule      string            `yaml:"schedule"`                // Cron schedule for this instance's backups (targets may override it)
 	Retention     string            `yaml:"retention,omitempty"`     // Optional: instance-specific retention (overrides global)
 	ResticTimeout string            `yaml:"resticTimeout,omitempty"` // Optional: instance-specific timeout (overrides global)
 	Env           map[string]string `yaml:"env,omitempty"`           // Environment variables passed to backend
 	Targets       []TargetConfig    `yaml:"targets,omitempty"`       // List of backup targets (volumes and databases)
+	Helper        HelperConfig      `yaml:"helper,omitempty"`        // Optional: instance-specific helper container settings (overrides global per field)
+	Overlap       string            `yaml:"overlap,omitempty"`       // What to do if a run is due while the previous one is active: skip (default), queue or cancel-previous
+	Retry         RetryConfig       `yaml:"retry,omitempty"`         // Optional: retries of failed target staging and uploads (default: no retries)
+	Timezone      string            `yaml:"timezone,omitempty"`      // Optional: IANA time zone the schedules are evaluated in (overrides global)
+	Jitter        string            `yaml:"jitter,omitempty"`        // Optional: maximum random delay of scheduled runs (overrides global)
+	CatchUp       bool              `yaml:"catchUp,omitempty"`       // Optional: start a run after startup if a scheduled run was missed (default: false)
+	Blackouts     []BlackoutConfig  `yaml:"blackouts,omitempty"`     // Optional: windows in which scheduled runs do not start (in addition to global ones)
+	After         []string          `yaml:"after,omitempty"`         // Optional: run when these instances have completed successfully (instead of a schedule)
+
+	JobTimeout     string `yaml:"jobTimeout,omitempty"`     // Optional: limit of a whole run (overrides global)
+	HookTimeout    string `yaml:"hookTimeout,omitempty"`    // Optional: limit of each pre- and post-hook (overrides global)
+	DumpTimeout    string `yaml:"dumpTimeout,omitempty"`    // Optional: limit of each database dump (overrides global)
+	StagingTimeout string `yaml:"stagingTimeout,omitempty"` // Optional: limit of staging each volume (overrides global)
+}
+
+// RetryConfig configures retries with exponential backoff
+type RetryConfig struct {
+	MaxAttempts    int     `yaml:"maxAttempts,omitempty"`    // Attempts per step including the first (default: 1 = no retries)
+	InitialBackoff string  `yaml:"initialBackoff,omitempty"` // Wait before the first retry (default: "30s")
+	Multiplier     float64 `yaml:"multiplier,omitempty"`     // Factor applied to the wait after every retry (default: 2)
+}
+
+// BlackoutConfig defines a recurring window in which scheduled runs do not start:
+// either a cron window (cron and duration) or a time range (start and end, optionally on certain days)
+type BlackoutConfig struct {
+	Cron     string   `yaml:"cron,omitempty"`     // Cron window: opens at every tick of this schedule
+	Duration string   `yaml:"duration,omitempty"` // Cron window: how long it stays open (e.g., "4h")
+	Days     []string `yaml:"days,omitempty"`     // Time range: days it opens on (mon, tue, ...; default: every day)
+	Start    string   `yaml:"start,omitempty"`    // Time range: opening time ("HH:MM")
+	End      string   `yaml:"end,omitempty"`      // Time range: closing time ("HH:MM"; at or before start means the next day)
+	Policy   string   `yaml:"policy,omitempty"`   // What happens to runs due in the window: defer (default) or skip
 }
 
 // TargetConfig represents a backup target configuration
@@ -40,10 +97,14 @@ type TargetConfig struct {
 	DB           string   `yaml:"db,omitempty"`           // Container name for database (mutually exclusive with Volume)
 	Paths        []string `yaml:"paths,omitempty"`        // Paths to backup (for volumes, default: ["/"])
 	StopAttached *bool    `yaml:"stopAttached,omitempty"` // Stop containers using volume (for volumes)
+	Quiesce      string   `yaml:"quiesce,omitempty"`      // How to quiesce attached containers: pause, stop or none (for volumes, overrides stopAttached)
+	Staging      string   `yaml:"staging,omitempty"`      // Staging strategy: copy (default), snapshot, direct or incremental for volumes; copy or stream for databases
+	Snapshot     string   `yaml:"snapshot,omitempty"`     // Snapshot driver for staging: snapshot - btrfs, zfs or lvm (for volumes)
 	PreHook      string   `yaml:"preHook,omitempty"`      // Command to run before backup
 	PostHook     string   `yaml:"postHook,omitempty"`     // Command to run after backup
 	DBKind       string   `yaml:"dbKind,omitempty"`       // Database type: postgres, mysql, mariadb, mongo, redis (auto-detected if not provided)
 	DumpArgs     []string `yaml:"dumpArgs,omitempty"`     // Arguments for database dump command
+	Schedule     string   `yaml:"schedule,omitempty"`     // Optional: target-specific cron schedule (overrides the instance schedule)
 }
 
 // Load reads and parses the config file, expanding environment variables
@@ -63,8 +124,17 @@ func Load(path string) (*Config, error) {
 		cfg.Instances[i].Repository = expandEnv(cfg.Instances[i].Repository)
 		cfg.Instances[i].CustomImage = expandEnv(cfg.Instances[i].CustomImage)
 		cfg.Instances[i].Schedule = expandEnv(cfg.Instances[i].Schedule)
+		cfg.Instances[i].Timezone = expandEnv(cfg.Instances[i].Timezone)
+		cfg.Instances[i].Jitter = expandEnv(cfg.Instances[i].Jitter)
 		cfg.Instances[i].Retention = expandEnv(cfg.Instances[i].Retention)
 		cfg.Instances[i].ResticTimeout = expandEnv(cfg.Instances[i].ResticTimeout)
+		cfg.Instances[i].JobTimeout = expandEnv(cfg.Instances[i].JobTimeout)
+		cfg.Instances[i].HookTimeout = expandEnv(cfg.Instances[i].HookTimeout)
+		cfg.Instances[i].DumpTimeout = expandEnv(cfg.Instances[i].DumpTimeout)
+		cfg.Instances[i].StagingTimeout = expandEnv(cfg.Instances[i].StagingTimeout)
+		cfg.Instances[i].Helper.Image = expandEnv(cfg.Instances[i].Helper.Image)
+		cfg.Instances[i].Helper.Memory = expandEnv(cfg.Instances[i].Helper.Memory)
+		expandBlackouts(cfg.Instances[i].Blackouts)
 		for k, v := range cfg.Instances[i].Env {
 			cfg.Instances[i].Env[k] = expandEnv(v)
 		}
@@ -75,6 +145,10 @@ func Load(path string) (*Config, error) {
 			cfg.Instances[i].Targets[j].PreHook = expandEnv(cfg.Instances[i].Targets[j].PreHook)
 			cfg.Instances[i].Targets[j].PostHook = expandEnv(cfg.Instances[i].Targets[j].PostHook)
 			cfg.Instances[i].Targets[j].DBKind = expandEnv(cfg.Instances[i].Targets[j].DBKind)
+			cfg.Instances[i].Targets[j].Quiesce = expandEnv(cfg.Instances[i].Targets[j].Quiesce)
+			cfg.Instances[i].Targets[j].Staging = expandEnv(cfg.Instances[i].Targets[j].Staging)
+			cfg.Instances[i].Targets[j].Snapshot = expandEnv(cfg.Instances[i].Targets[j].Snapshot)
+			cfg.Instances[i].Targets[j].Schedule = expandEnv(cfg.Instances[i].Targets[j].Schedule)
 			for k := range cfg.Instances[i].Targets[j].Paths {
 				cfg.Instances[i].Targets[j].Paths[k] = expandEnv(cfg.Instances[i].Targets[j].Paths[k])
 			}
@@ -89,6 +163,16 @@ func Load(path string) (*Config, error) {
 	cfg.APIPort = expandEnv(cfg.APIPort)
 	cfg.NodeName = expandEnv(cfg.NodeName)
 	cfg.AuthPassword = expandEnv(cfg.AuthPassword)
+	cfg.Helper.Image = expandEnv(cfg.Helper.Image)
+	cfg.Helper.Memory = expandEnv(cfg.Helper.Memory)
+	cfg.Timezone = expandEnv(cfg.Timezone)
+	cfg.Jitter = expandEnv(cfg.Jitter)
+	cfg.JobTimeout = expandEnv(cfg.JobTimeout)
+	cfg.HookTimeout = expandEnv(cfg.HookTimeout)
+	cfg.DumpTimeout = expandEnv(cfg.DumpTimeout)
+	cfg.StagingTimeout = expandEnv(cfg.StagingTimeout)
+	cfg.ShutdownTimeout = expandEnv(cfg.ShutdownTimeout)
+	expandBlackouts(cfg.Blackouts)
 	for i := range cfg.CorsOrigins {
 		cfg.CorsOrigins[i] = expandEnv(cfg.CorsOrigins[i])
 	}
@@ -96,9 +180,121 @@ func Load(path string) (*Config, error) {
 		cfg.Peers[i] = expandEnv(cfg.Peers[i])
 	}
 
+	if err := cfg.validateSchedules(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateChains(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// validateSchedules checks time zones and all instance and target schedules with the
+// scheduler's cron parser, so mistakes are reported when the config is loaded
+func (c *Config) validateSchedules() error {
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+		}
+	}
+
+	for _, inst := range c.Instances {
+		timezone := c.Timezone
+		if inst.Timezone != "" {
+			if _, err := time.LoadLocation(inst.Timezone); err != nil {
+				return fmt.Errorf("instance %s: invalid timezone %q: %w", inst.ID, inst.Timezone, err)
+			}
+			timezone = inst.Timezone
+		}
+		if inst.Schedule != "" {
+			if err := helpers.ValidateCron(helpers.CronSpec(inst.Schedule, timezone)); err != nil {
+				return fmt.Errorf("instance %s: invalid schedule: %w", inst.ID, err)
+			}
+		}
+		for j, target := range inst.Targets {
+			if target.Schedule != "" {
+				if err := helpers.ValidateCron(helpers.CronSpec(target.Schedule, timezone)); err != nil {
+					return fmt.Errorf("instance %s target #%d: invalid schedule: %w", inst.ID, j+1, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateChains checks the 'after' dependencies between instances: they must reference other
+// configured instances, cannot be combined with schedules and must not form a cycle
+func (c *Config) validateChains() error {
+	after := make(map[string][]string, len(c.Instances))
+	for _, inst := range c.Instances {
+		after[inst.ID] = inst.After
+	}
+
+	for _, inst := range c.Instances {
+		if len(inst.After) == 0 {
+			continue
+		}
+		if inst.Schedule != "" || slices.ContainsFunc(inst.Targets, func(t TargetConfig) bool { return t.Schedule != "" }) {
+			return fmt.Errorf("instance %s: 'after' cannot be combined with a schedule", inst.ID)
+		}
+		for _, upstream := range inst.After {
+			if _, ok := after[upstream]; !ok {
+				return fmt.Errorf("instance %s: 'after' references unknown instance %q", inst.ID, upstream)
+			}
+		}
+	}
+
+	// Depth-first search; a path that reaches an instance already on it is a cycle
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(after))
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			cycle := append(path[slices.Index(path, id):], id)
+			return fmt.Errorf("instance %s: 'after' dependencies form a cycle: %s", id, strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		path = append(path, id)
+		for _, upstream := range after[id] {
+			if err := visit(upstream); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+	for _, inst := range c.Instances {
+		if err := visit(inst.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandBlackouts expands environment variables in blackout windows
+func expandBlackouts(windows []BlackoutConfig) {
+	for i := range windows {
+		windows[i].Cron = expandEnv(windows[i].Cron)
+		windows[i].Duration = expandEnv(windows[i].Duration)
+		windows[i].Start = expandEnv(windows[i].Start)
+		windows[i].End = expandEnv(windows[i].End)
+		windows[i].Policy = expandEnv(windows[i].Policy)
+		for k := range windows[i].Days {
+			windows[i].Days[k] = expandEnv(windows[i].Days[k])
+		}
+	}
+}
+
 // expandEnv expands environment variable references in the format ${VAR} or $VAR
 func expandEnv(s string) string {
 	// Match ${VAR} or $VAR patterns
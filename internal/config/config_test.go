@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -329,6 +330,7 @@ func TestLoad_RuntimeConfigEnvExpansion(t *testing.T) {
 	t.Setenv("API_PORT", "9090")
 	t.Setenv("CORS_ORIGIN_1", "https://app1.example.com")
 	t.Setenv("CORS_ORIGIN_2", "https://app2.example.com")
+	t.Setenv("SHUTDOWN_TIMEOUT", "90s")
 	cfgYAML := `
  instances:
    - id: test
@@ -340,6 +342,7 @@ func TestLoad_RuntimeConfigEnvExpansion(t *testing.T) {
        - volume: app-data
  dbPath: ${DB_PATH}
  apiPort: ${API_PORT}
+ shutdownTimeout: ${SHUTDOWN_TIMEOUT}
  corsOrigins:
    - ${CORS_ORIGIN_1}
    - ${CORS_ORIGIN_2}
@@ -355,6 +358,9 @@ func TestLoad_RuntimeConfigEnvExpansion(t *testing.T) {
 	if cfg.APIPort != "9090" {
 		t.Fatalf("apiPort not expanded: %q", cfg.APIPort)
 	}
+	if cfg.ShutdownTimeout != "90s" {
+		t.Fatalf("shutdownTimeout not expanded: %q", cfg.ShutdownTimeout)
+	}
 	if len(cfg.CorsOrigins) != 2 {
 		t.Fatalf("expected 2 CORS origins, got %d", len(cfg.CorsOrigins))
 	}
@@ -365,3 +371,211 @@ func TestLoad_RuntimeConfigEnvExpansion(t *testing.T) {
 		t.Fatalf("CORS origin 2 not expanded: %q", cfg.CorsOrigins[1])
 	}
 }
+
+func TestLoad_HelperConfig(t *testing.T) {
+	t.Setenv("HELPER_IMAGE", "registry.local/alpine:3.20")
+	cfgYAML := `
+ instances:
+   - id: limited
+     repository: /tmp/backup1
+     schedule: "0 2 * * *"
+     helper:
+       memory: 512m
+     targets:
+       - volume: app-data
+ helper:
+   image: ${HELPER_IMAGE}
+   cpus: 0.5
+`
+	p := writeTempConfig(t, cfgYAML)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Helper.Image != "registry.local/alpine:3.20" || cfg.Helper.CPUs != 0.5 {
+		t.Fatalf("global helper not parsed: %#v", cfg.Helper)
+	}
+	d, err := cfg.GetDestination("limited")
+	if err != nil {
+		t.Fatalf("GetDestination error: %v", err)
+	}
+	if d.Helper.Memory != "512m" || d.Helper.Image != "" {
+		t.Fatalf("instance helper not parsed: %#v", d.Helper)
+	}
+}
+
+func TestLoad_ValidatesSchedules(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "descriptors, seconds and time zones",
+			yaml: `
+timezone: Europe/Berlin
+instances:
+  - id: a
+    schedule: "@every 6h"
+    targets:
+      - volume: data
+      - db: postgres
+        schedule: "30 0 * * * *"
+  - id: b
+    schedule: "@daily"
+    timezone: America/New_York
+    targets:
+      - volume: data
+`,
+		},
+		{
+			name: "invalid instance schedule",
+			yaml: `
+instances:
+  - id: a
+    schedule: "0 25 * * *"
+    targets:
+      - volume: data
+`,
+			wantErr: "instance a: invalid schedule",
+		},
+		{
+			name: "invalid target schedule",
+			yaml: `
+instances:
+  - id: a
+    schedule: "0 3 * * *"
+    targets:
+      - volume: data
+        schedule: "@hourly-ish"
+`,
+			wantErr: "instance a target #1: invalid schedule",
+		},
+		{
+			name: "invalid instance timezone",
+			yaml: `
+instances:
+  - id: a
+    schedule: "0 3 * * *"
+    timezone: Mars/Olympus
+    targets:
+      - volume: data
+`,
+			wantErr: `instance a: invalid timezone "Mars/Olympus"`,
+		},
+		{
+			name: "invalid global timezone",
+			yaml: `
+timezone: Nowhere
+instances: []
+`,
+			wantErr: `invalid timezone "Nowhere"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeTempConfig(t, tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ValidatesChains(t *testing.T) {
+	instance := func(id, schedule, after string) string {
+		s := "\n  - id: " + id + "\n    targets:\n      - volume: data"
+		if schedule != "" {
+			s += "\n    schedule: \"" + schedule + "\""
+		}
+		if after != "" {
+			s += "\n    after: [" + after + "]"
+		}
+		return s
+	}
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "chain",
+			yaml: "instances:" + instance("export", "0 2 * * *", "") + instance("upload", "", "export") + instance("offsite", "", "export, upload"),
+		},
+		{
+			name:    "unknown upstream",
+			yaml:    "instances:" + instance("upload", "", "export"),
+			wantErr: `instance upload: 'after' references unknown instance "export"`,
+		},
+		{
+			name:    "combined with schedule",
+			yaml:    "instances:" + instance("export", "0 2 * * *", "") + instance("upload", "0 3 * * *", "export"),
+			wantErr: "instance upload: 'after' cannot be combined with a schedule",
+		},
+		{
+			name:    "self reference",
+			yaml:    "instances:" + instance("a", "", "a"),
+			wantErr: "cycle: a -> a",
+		},
+		{
+			name:    "cycle",
+			yaml:    "instances:" + instance("a", "", "c") + instance("b", "", "a") + instance("c", "", "b"),
+			wantErr: "cycle: a -> c -> b -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeTempConfig(t, tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_BlackoutEnvExpansion(t *testing.T) {
+	t.Setenv("BLACKOUT_START", "08:00")
+	t.Setenv("BLACKOUT_POLICY", "skip")
+	t.Setenv("RELEASE_WINDOW", "4h")
+	cfgYAML := `
+ instances:
+   - id: test
+     repository: /tmp/backup
+     schedule: "0 2 * * *"
+     blackouts:
+       - cron: "0 22 * * fri"
+         duration: ${RELEASE_WINDOW}
+     targets:
+       - volume: app-data
+ blackouts:
+   - days: [mon, fri]
+     start: ${BLACKOUT_START}
+     end: "18:00"
+     policy: $BLACKOUT_POLICY
+`
+	p := writeTempConfig(t, cfgYAML)
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := cfg.Blackouts[0]; got.Start != "08:00" || got.Policy != "skip" {
+		t.Fatalf("global blackout not expanded: %#v", got)
+	}
+	if got := cfg.Instances[0].Blackouts[0]; got.Duration != "4h" {
+		t.Fatalf("instance blackout not expanded: %#v", got)
+	}
+}
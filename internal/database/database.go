@@ -3,6 +3,8 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -29,7 +31,9 @@ func InitDB(dbPath string) (*DB, error) {
 			time.Sleep(delay)
 		}
 
-		db, err = sql.Open("sqlite", dbPath)
+		// busy_timeout is set per connection, so it goes into the DSN: every pooled connection
+		// waits for concurrent writers instead of failing with SQLITE_BUSY
+		db, err = sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(10000)")
 		if err != nil {
 			if attempt == maxRetries-1 {
 				return nil, fmt.Errorf("failed to open database after %d attempts: %w", maxRetries, err)
@@ -88,7 +92,8 @@ func InitDB(dbPath string) (*DB, error) {
 	return nil, fmt.Errorf("failed to initialize database after %d attempts: %w", maxRetries, err)
 }
 
-// CleanupInterruptedJobs resets any jobs that were interrupted by a restart
+// CleanupInterruptedJobs resets any jobs that were interrupted by a restart.
+// Queued jobs are kept; the runner resumes them (see GetQueuedJobs).
 func (d *DB) CleanupInterruptedJobs(ctx context.Context) (int, error) {
 	query := `
 		UPDATE job_status 
@@ -129,6 +134,9 @@ func createSchema(db *sql.DB) error {
 		last_targets_total INTEGER DEFAULT 0,
 		last_started_at TIMESTAMP,
 		last_completed_at TIMESTAMP,
+		message TEXT DEFAULT '',
+		attempts INTEGER DEFAULT 0,
+		target_ids TEXT DEFAULT '',
 		created_at TIMESTAMP NOT NULL,
 		updated_at TIMESTAMP NOT NULL
 	);
@@ -169,6 +177,56 @@ func createSchema(db *sql.DB) error {
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_backup_schedules_instance_id ON backup_schedules(instance_id);
+
+	CREATE TABLE IF NOT EXISTS staging_sizes (
+		instance_id TEXT NOT NULL,
+		target_id TEXT NOT NULL,
+		job_status_id INTEGER,
+		estimated_bytes INTEGER DEFAULT -1,
+		staged_bytes INTEGER DEFAULT 0,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (instance_id, target_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS job_targets (
+		job_status_id INTEGER NOT NULL,
+		target_id TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		status TEXT NOT NULL,
+		message TEXT DEFAULT '',
+		staged_bytes INTEGER DEFAULT 0,
+		staging_duration_ms INTEGER DEFAULT 0,
+		db_kind TEXT DEFAULT '',
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (job_status_id, target_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS job_phases (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		job_status_id INTEGER NOT NULL,
+		target_id TEXT DEFAULT '',
+		phase TEXT NOT NULL,
+		started_at TIMESTAMP NOT NULL,
+		ended_at TIMESTAMP NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		error TEXT DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_job_phases_job_status_id ON job_phases(job_status_id);
+
+	CREATE TABLE IF NOT EXISTS control_commands (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		command TEXT NOT NULL,
+		job_status_id INTEGER DEFAULT 0,
+		instance_id TEXT DEFAULT '',
+		target_ids TEXT DEFAULT '',
+		result TEXT DEFAULT '',
+		report TEXT DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		processed_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_control_commands_processed_at ON control_commands(processed_at);
 	`
 
 	_, err := db.Exec(schema)
@@ -176,6 +234,47 @@ func createSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
 
+	// Columns added after the first release (CREATE TABLE IF NOT EXISTS keeps old tables as they are)
+	if err := addColumnIfMissing(db, "job_status", "message", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "job_status", "attempts", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "job_status", "target_ids", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table unless it already exists
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan columns of %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -189,6 +288,197 @@ func (d *DB) GetDB() *sql.DB {
 	return d.db
 }
 
+// RecordStagingSize stores the estimated and actual staged size of a target, replacing the previous run's values
+func (d *DB) RecordStagingSize(ctx context.Context, size model.StagingSize) error {
+	query := `
+		INSERT INTO staging_sizes (instance_id, target_id, job_status_id, estimated_bytes, staged_bytes, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(instance_id, target_id) DO UPDATE SET
+			job_status_id = excluded.job_status_id,
+			estimated_bytes = excluded.estimated_bytes,
+			staged_bytes = excluded.staged_bytes,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := d.db.ExecContext(ctx, query, size.InstanceID, size.TargetID, size.JobStatusID, size.EstimatedBytes, size.StagedBytes, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record staging size for %s/%s: %w", size.InstanceID, size.TargetID, err)
+	}
+
+	return nil
+}
+
+// SaveJobTarget stores the result of a target of a job, replacing an earlier result of the same target
+func (d *DB) SaveJobTarget(ctx context.Context, target model.JobTarget) error {
+	query := `
+		INSERT INTO job_targets (job_status_id, target_id, target_type, status, message, staged_bytes, staging_duration_ms, db_kind, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(job_status_id, target_id) DO UPDATE SET
+			target_type = excluded.target_type,
+			status = excluded.status,
+			message = excluded.message,
+			staged_bytes = excluded.staged_bytes,
+			staging_duration_ms = excluded.staging_duration_ms,
+			db_kind = excluded.db_kind,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := d.db.ExecContext(ctx, query, target.JobStatusID, target.TargetID, target.Type, target.Status, target.Message,
+		target.StagedBytes, target.StagingDurationMs, target.DBKind, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save result of target %s of job %d: %w", target.TargetID, target.JobStatusID, err)
+	}
+
+	return nil
+}
+
+// GetJobTargets returns the target results of a job in the order they were first recorded
+func (d *DB) GetJobTargets(ctx context.Context, jobStatusID int) ([]*model.JobTarget, error) {
+	query := `
+	SELECT job_status_id, target_id, target_type, status, COALESCE(message, ''), staged_bytes, staging_duration_ms, COALESCE(db_kind, ''), updated_at
+	FROM job_targets
+	WHERE job_status_id = ?
+	ORDER BY rowid ASC
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, jobStatusID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []*model.JobTarget{}
+	for rows.Next() {
+		target := &model.JobTarget{}
+		if err := rows.Scan(&target.JobStatusID, &target.TargetID, &target.Type, &target.Status, &target.Message,
+			&target.StagedBytes, &target.StagingDurationMs, &target.DBKind, &target.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan job target: %w", err)
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, rows.Err()
+}
+
+// RecordJobPhase stores a finished phase of a job
+func (d *DB) RecordJobPhase(ctx context.Context, phase model.JobPhase) error {
+	query := `
+		INSERT INTO job_phases (job_status_id, target_id, phase, started_at, ended_at, duration_ms, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.ExecContext(ctx, query, phase.JobStatusID, phase.TargetID, phase.Phase,
+		phase.StartedAt, phase.EndedAt, phase.DurationMs, phase.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record phase %s of job %d: %w", phase.Phase, phase.JobStatusID, err)
+	}
+
+	return nil
+}
+
+// GetJobPhases returns the phases of a job in the order they started
+func (d *DB) GetJobPhases(ctx context.Context, jobStatusID int) ([]*model.JobPhase, error) {
+	query := `
+	SELECT job_status_id, COALESCE(target_id, ''), phase, started_at, ended_at, duration_ms, COALESCE(error, '')
+	FROM job_phases
+	WHERE job_status_id = ?
+	ORDER BY started_at ASC, id ASC
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, jobStatusID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job phases: %w", err)
+	}
+	defer rows.Close()
+
+	phases := []*model.JobPhase{}
+	for rows.Next() {
+		phase := &model.JobPhase{}
+		if err := rows.Scan(&phase.JobStatusID, &phase.TargetID, &phase.Phase,
+			&phase.StartedAt, &phase.EndedAt, &phase.DurationMs, &phase.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan job phase: %w", err)
+		}
+		phases = append(phases, phase)
+	}
+
+	return phases, rows.Err()
+}
+
+// GetPhaseTotals returns the total duration of each phase for the most recent jobs of an instance
+// that recorded phases, newest first
+func (d *DB) GetPhaseTotals(ctx context.Context, instanceID model.InstanceID, limit int) ([]*model.JobPhaseTotals, error) {
+	query := `
+	SELECT j.id, j.status, j.last_started_at, p.phase, SUM(p.duration_ms)
+	FROM job_phases p
+	JOIN job_status j ON j.id = p.job_status_id
+	WHERE p.job_status_id IN (
+		SELECT id FROM job_status
+		WHERE instance_id = ? AND id IN (SELECT job_status_id FROM job_phases)
+		ORDER BY id DESC
+		LIMIT ?
+	)
+	GROUP BY j.id, p.phase
+	ORDER BY j.id DESC
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, instanceID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query phase totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []*model.JobPhaseTotals{}
+	var current *model.JobPhaseTotals
+	for rows.Next() {
+		var jobID int
+		var status model.JobStatusState
+		var startedAt *time.Time
+		var phase model.JobPhaseName
+		var durationMs int64
+		if err := rows.Scan(&jobID, &status, &startedAt, &phase, &durationMs); err != nil {
+			return nil, fmt.Errorf("failed to scan phase totals: %w", err)
+		}
+		if current == nil || current.JobStatusID != jobID {
+			current = &model.JobPhaseTotals{
+				JobStatusID:   jobID,
+				Status:        status,
+				LastStartedAt: startedAt,
+				DurationsMs:   make(map[model.JobPhaseName]int64),
+			}
+			totals = append(totals, current)
+		}
+		current.DurationsMs[phase] = durationMs
+	}
+
+	return totals, rows.Err()
+}
+
+// GetStagingSizes returns the staging sizes recorded for an instance's targets, keyed by target ID
+func (d *DB) GetStagingSizes(ctx context.Context, instanceID string) (map[string]model.StagingSize, error) {
+	query := `
+	SELECT instance_id, target_id, COALESCE(job_status_id, 0), estimated_bytes, staged_bytes, updated_at
+	FROM staging_sizes
+	WHERE instance_id = ?
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query staging sizes: %w", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]model.StagingSize)
+	for rows.Next() {
+		var size model.StagingSize
+		if err := rows.Scan(&size.InstanceID, &size.TargetID, &size.JobStatusID, &size.EstimatedBytes, &size.StagedBytes, &size.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan staging size: %w", err)
+		}
+		sizes[size.TargetID] = size
+	}
+
+	return sizes, rows.Err()
+}
+
 func (d *DB) UpdateNextRunTime(ctx context.Context, instanceID string, nextRunTime *time.Time) error {
 	query := `
 		UPDATE backup_schedules
@@ -353,14 +643,16 @@ func (d *DB) GetAllSchedules(ctx context.Context) ([]*model.InstanceBackupSchedu
 	return schedules, rows.Err()
 }
 
-func (d *DB) ScheduleNewJob(ctx context.Context, instanceID string) (*model.JobStatus, error) {
+// ScheduleNewJob creates the job status record of a new run covering the given targets
+func (d *DB) ScheduleNewJob(ctx context.Context, instanceID string, targetIDs []string) (*model.JobStatus, error) {
 	query := `
 	INSERT INTO job_status (
 		instance_id, iid, is_active, status,
 		last_started_at, last_completed_at,
 		last_targets_successful, last_targets_total,
+		target_ids,
 		created_at, updated_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	// iid is next available integer ID for the instance
@@ -370,7 +662,7 @@ func (d *DB) ScheduleNewJob(ctx context.Context, instanceID string) (*model.JobS
 		return nil, fmt.Errorf("failed to get next iid: %w", err)
 	}
 
-	result, err := d.db.ExecContext(ctx, query, instanceID, iid, 1, model.StatusScheduled, nil, nil, 0, 0, time.Now(), time.Now())
+	result, err := d.db.ExecContext(ctx, query, instanceID, iid, 1, model.StatusScheduled, nil, nil, 0, len(targetIDs), strings.Join(targetIDs, ","), time.Now(), time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to start new job: %w", err)
 	}
@@ -395,6 +687,8 @@ func (d *DB) UpdateJobStatus(ctx context.Context, status *model.JobStatus) error
 		last_completed_at = ?,
 		last_targets_successful = ?,
 		last_targets_total = ?,
+		message = ?,
+		attempts = ?,
 		updated_at = ?
 	WHERE id = ?
 	`
@@ -405,6 +699,8 @@ func (d *DB) UpdateJobStatus(ctx context.Context, status *model.JobStatus) error
 		status.LastCompletedAt,
 		status.LastTargetsSuccessful,
 		status.LastTargetsTotal,
+		status.Message,
+		status.Attempts,
 		status.UpdatedAt,
 		status.ID,
 	)
@@ -415,13 +711,38 @@ func (d *DB) UpdateJobStatus(ctx context.Context, status *model.JobStatus) error
 	return nil
 }
 
+// jobStatusColumns are the job_status columns read by scanJobStatus, in order
+const jobStatusColumns = `id, iid, instance_id, is_active, status,
+		last_started_at, last_completed_at,
+		last_targets_successful, last_targets_total,
+		COALESCE(message, ''), COALESCE(attempts, 0), COALESCE(target_ids, ''),
+		created_at, updated_at`
+
+// scanJobStatus scans a row selected with jobStatusColumns
+func scanJobStatus(row interface{ Scan(...any) error }) (*model.JobStatus, error) {
+	status := &model.JobStatus{}
+	var targetIDs string
+	err := row.Scan(
+		&status.ID, &status.IID,
+		&status.InstanceID, &status.IsActive, &status.Status,
+		&status.LastStartedAt, &status.LastCompletedAt,
+		&status.LastTargetsSuccessful, &status.LastTargetsTotal,
+		&status.Message, &status.Attempts, &targetIDs,
+		&status.CreatedAt, &status.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan job status: %w", err)
+	}
+	if targetIDs != "" {
+		status.TargetIDs = strings.Split(targetIDs, ",")
+	}
+	return status, nil
+}
+
 // GetJobStatus retrieves all job statuses for a given instance ID
 func (d *DB) GetJobStatus(ctx context.Context, instanceID string) ([]*model.JobStatus, error) {
 	query := `
-	SELECT id, iid, instance_id, is_active, status,
-		last_started_at, last_completed_at,
-		last_targets_successful, last_targets_total,
-		created_at, updated_at
+	SELECT ` + jobStatusColumns + `
 	FROM job_status
 	WHERE instance_id = ?
 	ORDER BY id DESC
@@ -436,16 +757,64 @@ func (d *DB) GetJobStatus(ctx context.Context, instanceID string) ([]*model.JobS
 	// Initialize as empty slice so JSON encodes as [] instead of null
 	statuses := make([]*model.JobStatus, 0)
 	for rows.Next() {
-		status := &model.JobStatus{}
-		err := rows.Scan(
-			&status.ID, &status.IID,
-			&status.InstanceID, &status.IsActive, &status.Status,
-			&status.LastStartedAt, &status.LastCompletedAt,
-			&status.LastTargetsSuccessful, &status.LastTargetsTotal,
-			&status.CreatedAt, &status.UpdatedAt,
-		)
+		status, err := scanJobStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, rows.Err()
+}
+
+// GetQueuedJobs retrieves all active jobs waiting in the run queue, oldest first
+func (d *DB) GetQueuedJobs(ctx context.Context) ([]*model.JobStatus, error) {
+	query := `
+	SELECT ` + jobStatusColumns + `
+	FROM job_status
+	WHERE status = ? AND is_active = 1
+	ORDER BY id ASC
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, model.StatusQueued)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queued jobs: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make([]*model.JobStatus, 0)
+	for rows.Next() {
+		status, err := scanJobStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, rows.Err()
+}
+
+// GetCompletedJobs retrieves the jobs of an instance that ran to completion
+// (success, partial success or failure), newest first
+func (d *DB) GetCompletedJobs(ctx context.Context, instanceID string) ([]*model.JobStatus, error) {
+	query := `
+	SELECT ` + jobStatusColumns + `
+	FROM job_status
+	WHERE instance_id = ? AND status IN (?, ?, ?) AND last_started_at IS NOT NULL
+	ORDER BY id DESC
+	`
+
+	rows, err := d.db.QueryContext(ctx, query, instanceID, model.StatusSuccess, model.StatusPartialSuccess, model.StatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query completed jobs: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make([]*model.JobStatus, 0)
+	for rows.Next() {
+		status, err := scanJobStatus(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan job status: %w", err)
+			return nil, err
 		}
 		statuses = append(statuses, status)
 	}
@@ -453,32 +822,118 @@ func (d *DB) GetJobStatus(ctx context.Context, instanceID string) ([]*model.JobS
 	return statuses, rows.Err()
 }
 
+// AddControlCommand stores a command for the manager (see GetPendingControlCommands) and returns its ID
+func (d *DB) AddControlCommand(ctx context.Context, cmd model.ControlCommand) (int, error) {
+	result, err := d.db.ExecContext(ctx, `
+	INSERT INTO control_commands (command, job_status_id, instance_id, target_ids, created_at)
+	VALUES (?, ?, ?, ?, ?)
+	`, cmd.Type, cmd.JobStatusID, cmd.InstanceID, strings.Join(cmd.TargetIDs, ","), time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to add control command: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get control command ID: %w", err)
+	}
+	return int(id), nil
+}
+
+// controlCommandColumns are the control_commands columns read by scanControlCommand, in order
+const controlCommandColumns = `id, command, COALESCE(job_status_id, 0), COALESCE(instance_id, ''),
+		COALESCE(target_ids, ''), COALESCE(result, ''), COALESCE(report, ''), created_at, processed_at`
+
+// scanControlCommand scans a row selected with controlCommandColumns
+func scanControlCommand(row interface{ Scan(...any) error }) (*model.ControlCommand, error) {
+	cmd := &model.ControlCommand{}
+	var targetIDs, report string
+	if err := row.Scan(&cmd.ID, &cmd.Type, &cmd.JobStatusID, &cmd.InstanceID, &targetIDs, &cmd.Result, &report, &cmd.CreatedAt, &cmd.ProcessedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan control command: %w", err)
+	}
+	if targetIDs != "" {
+		cmd.TargetIDs = strings.Split(targetIDs, ",")
+	}
+	if report != "" {
+		cmd.Report = &model.DryRunReport{}
+		if err := json.Unmarshal([]byte(report), cmd.Report); err != nil {
+			return nil, fmt.Errorf("failed to parse dry-run report of control command %d: %w", cmd.ID, err)
+		}
+	}
+	return cmd, nil
+}
+
+// GetPendingControlCommands retrieves the commands the manager has not processed yet, oldest first
+func (d *DB) GetPendingControlCommands(ctx context.Context) ([]*model.ControlCommand, error) {
+	rows, err := d.db.QueryContext(ctx, `
+	SELECT `+controlCommandColumns+`
+	FROM control_commands
+	WHERE processed_at IS NULL
+	ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query control commands: %w", err)
+	}
+	defer rows.Close()
+
+	var commands []*model.ControlCommand
+	for rows.Next() {
+		cmd, err := scanControlCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+	return commands, rows.Err()
+}
+
+// GetControlCommand retrieves a command by its ID (nil if it does not exist)
+func (d *DB) GetControlCommand(ctx context.Context, id int) (*model.ControlCommand, error) {
+	cmd, err := scanControlCommand(d.db.QueryRowContext(ctx, `
+	SELECT `+controlCommandColumns+`
+	FROM control_commands
+	WHERE id = ?
+	`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// CompleteControlCommand marks a command as processed with its outcome and the job it applies to
+// (for run commands, the job created by the manager). report is the result of a dry-run command (nil otherwise).
+func (d *DB) CompleteControlCommand(ctx context.Context, id, jobStatusID int, result string, report *model.DryRunReport) error {
+	var reportJSON string
+	if report != nil {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("failed to encode dry-run report: %w", err)
+		}
+		reportJSON = string(data)
+	}
+	if _, err := d.db.ExecContext(ctx, `
+	UPDATE control_commands SET processed_at = ?, job_status_id = ?, result = ?, report = ? WHERE id = ?
+	`, time.Now(), jobStatusID, result, reportJSON, id); err != nil {
+		return fmt.Errorf("failed to complete control command: %w", err)
+	}
+	return nil
+}
+
 // GetJobByID retrieves a job status by its ID
 func (d *DB) GetJobByID(ctx context.Context, jobID int) (*model.JobStatus, error) {
 	query := `
-	SELECT id, iid, instance_id, is_active, status,
-		last_started_at, last_completed_at,
-		last_targets_successful, last_targets_total,
-		created_at, updated_at
+	SELECT ` + jobStatusColumns + `
 	FROM job_status
 	WHERE id = ?
 	`
 
-	row := d.db.QueryRowContext(ctx, query, jobID)
-
-	status := &model.JobStatus{}
-	err := row.Scan(
-		&status.ID, &status.IID,
-		&status.InstanceID, &status.IsActive, &status.Status,
-		&status.LastStartedAt, &status.LastCompletedAt,
-		&status.LastTargetsSuccessful, &status.LastTargetsTotal,
-		&status.CreatedAt, &status.UpdatedAt,
-	)
+	status, err := scanJobStatus(d.db.QueryRowContext(ctx, query, jobID))
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to scan job status: %w", err)
+		return nil, err
 	}
 
 	return status, nil
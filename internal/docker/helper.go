@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// helperCapabilities are the only capabilities helper containers keep: enough for 'cp -a' and
+// 'rsync -a' to read every file, preserve ownership, permissions and timestamps, and recreate
+// fifos and device nodes (MKNOD)
+var helperCapabilities = []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "MKNOD"}
+
+// PrepareHelperImage pulls a helper image once so backup runs do not depend on registry access.
+// If the pull fails (e.g. on air-gapped hosts), a locally available image is used instead.
+func PrepareHelperImage(ctx context.Context, cli *client.Client, imageName string) error {
+	rc, err := cli.ImagePull(ctx, imageName, image.PullOptions{})
+	if err != nil {
+		if _, inspectErr := cli.ImageInspect(ctx, imageName); inspectErr != nil {
+			return fmt.Errorf("pull helper image %s failed: %w (also not present locally: %v)", imageName, err, inspectErr)
+		}
+		return nil
+	}
+	defer rc.Close()
+	_, _ = io.Copy(io.Discard, rc)
+	return nil
+}
+
+// helperHostConfig returns the host config for an unprivileged helper container: no network,
+// all capabilities dropped except helperCapabilities, read-only root filesystem (with a tmpfs
+// for /tmp), no privilege escalation and the configured CPU/memory limits
+func helperHostConfig(settings model.HelperSettings, mounts []mount.Mount) *container.HostConfig {
+	return &container.HostConfig{
+		Mounts:         mounts,
+		NetworkMode:    "none",
+		CapDrop:        []string{"ALL"},
+		CapAdd:         helperCapabilities,
+		ReadonlyRootfs: true,
+		Tmpfs:          map[string]string{"/tmp": ""},
+		SecurityOpt:    []string{"no-new-privileges"},
+		Resources: container.Resources{
+			NanoCPUs: int64(settings.CPUs * 1e9),
+			Memory:   settings.Memory,
+		},
+	}
+}
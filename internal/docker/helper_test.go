@@ -0,0 +1,44 @@
+package docker
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+func TestHelperHostConfig(t *testing.T) {
+	mounts := []mount.Mount{{Type: mount.TypeVolume, Source: "app", Target: "/src", ReadOnly: true}}
+	hc := helperHostConfig(model.HelperSettings{CPUs: 0.5, Memory: 256 << 20}, mounts)
+
+	if !slices.Equal(hc.CapDrop, []string{"ALL"}) {
+		t.Errorf("CapDrop = %v, want [ALL]", hc.CapDrop)
+	}
+	wantCaps := []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "MKNOD"}
+	if !slices.Equal(hc.CapAdd, wantCaps) {
+		t.Errorf("CapAdd = %v, want %v", hc.CapAdd, wantCaps)
+	}
+	if hc.NetworkMode != "none" {
+		t.Errorf("NetworkMode = %q, want none", hc.NetworkMode)
+	}
+	if !hc.ReadonlyRootfs {
+		t.Error("root filesystem is writable")
+	}
+	if _, ok := hc.Tmpfs["/tmp"]; !ok {
+		t.Errorf("Tmpfs = %v, want /tmp", hc.Tmpfs)
+	}
+	if !slices.Equal(hc.SecurityOpt, []string{"no-new-privileges"}) {
+		t.Errorf("SecurityOpt = %v", hc.SecurityOpt)
+	}
+	if hc.Privileged {
+		t.Error("helper runs privileged")
+	}
+	if hc.NanoCPUs != 5e8 || hc.Memory != 256<<20 {
+		t.Errorf("resources = %d CPUs (nano), %d bytes, want 5e8, %d", hc.NanoCPUs, hc.Memory, 256<<20)
+	}
+	if len(hc.Mounts) != 1 || hc.Mounts[0] != mounts[0] {
+		t.Errorf("Mounts = %v, want %v", hc.Mounts, mounts)
+	}
+}
@@ -0,0 +1,141 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// IncrementalCacheDir returns the persistent staging copy of a volume inside Marina's container
+// (/backup/<instance>/incremental/volume/<name>)
+func IncrementalCacheDir(instanceID, volumeName string) string {
+	return filepath.Join("/backup", instanceID, "incremental", "volume", volumeName)
+}
+
+// exitRsyncMissing is the exit code of the rsync helper if its image does not provide rsync
+const exitRsyncMissing = 127
+
+// rsyncImages returns the images to try for the rsync helper, in order: the configured helper image
+// (pulled like for the other helpers) unless it is the default, which has no rsync, then Marina's own image
+func rsyncImages(ctx context.Context, cli *client.Client, helperImage string) ([]string, error) {
+	var images []string
+	if helperImage != "" && helperImage != model.DefaultHelperImage {
+		if err := ensureImage(ctx, cli, helperImage); err != nil {
+			return nil, err
+		}
+		images = append(images, helperImage)
+	}
+	self, err := InspectSelf(ctx, cli)
+	if err != nil {
+		if len(images) > 0 {
+			return images, nil
+		}
+		return nil, err
+	}
+	return append(images, self.Image), nil
+}
+
+// SyncVolumeToStaging updates the persistent staging copy of a volume with 'rsync --delete', so only
+// changed files are copied, and then moves the copy into this run's staging directory
+// (/backup/<instance>/<timestamp>/volume/<name>). Backends see the same layout as with a full copy.
+// rsync runs in a helper container with the helper security profile and resource limits, using the
+// configured helper image if it provides rsync and Marina's own image (which ships rsync) otherwise.
+// Returns the staged paths and a release function that moves the copy back to its persistent
+// location. The release function must be called before the staging directory is removed.
+func SyncVolumeToStaging(ctx context.Context, cli *client.Client, helper model.HelperSettings, hostBackupPath, instanceID, timestamp, volumeName string, paths []string, logger *logging.JobLogger) ([]string, func(), error) {
+	cacheSubdir := fmt.Sprintf("%s/incremental/volume/%s", instanceID, volumeName)
+	cachePath := IncrementalCacheDir(instanceID, volumeName)
+	stagingPath := filepath.Join("/backup", instanceID, timestamp, "volume", volumeName)
+
+	if _, err := os.Stat(cachePath); err != nil {
+		logger.Info("no incremental copy of volume %s yet, doing a full sync", volumeName)
+	}
+
+	// Build one rsync call per path; --delete keeps the copy identical to the volume
+	var script strings.Builder
+	fmt.Fprintf(&script, "command -v rsync >/dev/null 2>&1 || { echo 'rsync not found' >&2; exit %d; }\n", exitRsyncMissing)
+	script.WriteString("set -e\n")
+	cleanPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		cleanPath := strings.TrimPrefix(path, "/")
+		if cleanPath == "" {
+			cleanPath = "."
+		}
+		cleanPaths = append(cleanPaths, cleanPath)
+		source := filepath.Join("/source", cleanPath)
+		target := filepath.Join("/backup", cacheSubdir, cleanPath)
+		fmt.Fprintf(&script, "mkdir -p %s\nrsync -a --delete --stats %s/ %s/\n", shellQuote(target), shellQuote(source), shellQuote(target))
+	}
+
+	images, err := rsyncImages(ctx, cli, helper.Image)
+	if err != nil {
+		return nil, nil, fmt.Errorf("incremental staging: %w", err)
+	}
+
+	config := &container.Config{
+		Entrypoint: []string{"sh", "-c"},
+		Cmd:        []string{script.String()},
+	}
+	hostConfig := helperHostConfig(helper, []mount.Mount{
+		{
+			Type:     mount.TypeVolume,
+			Source:   volumeName,
+			Target:   "/source",
+			ReadOnly: true,
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: hostBackupPath,
+			Target: "/backup",
+		},
+	})
+
+	logger.Debug("syncing volume %s into %s", volumeName, cachePath)
+	for i, image := range images {
+		config.Image = image
+		containerName := fmt.Sprintf("marina-rsync-%d", time.Now().UnixNano())
+		output, exitCode, err := RunToCompletion(ctx, cli, containerName, config, hostConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("run rsync container: %w", err)
+		}
+		if exitCode == exitRsyncMissing && i < len(images)-1 {
+			logger.Warn("helper image %s does not provide rsync, using %s for incremental staging", image, images[i+1])
+			continue
+		}
+		if exitCode != 0 {
+			return nil, nil, fmt.Errorf("rsync exited with code %d: %s", exitCode, strings.TrimSpace(output))
+		}
+		logger.Debug("rsync output: %s", output)
+		break
+	}
+
+	// Move the synced copy into this run's staging directory
+	if err := os.MkdirAll(filepath.Dir(stagingPath), 0755); err != nil {
+		return nil, nil, fmt.Errorf("create staging dir: %w", err)
+	}
+	if err := os.Rename(cachePath, stagingPath); err != nil {
+		return nil, nil, fmt.Errorf("move incremental copy into staging: %w", err)
+	}
+
+	release := func() {
+		if err := os.Rename(stagingPath, cachePath); err != nil {
+			logger.Warn("failed to keep incremental copy of volume %s (next run does a full sync): %v", volumeName, err)
+		}
+	}
+
+	stagedPaths := make([]string, 0, len(cleanPaths))
+	for _, cleanPath := range cleanPaths {
+		stagedPaths = append(stagedPaths, filepath.Join(stagingPath, cleanPath))
+	}
+	return stagedPaths, release, nil
+}
@@ -2,11 +2,13 @@ package docker
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +16,9 @@ import (
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
 )
 
 func ExecInContainer(ctx context.Context, cli *client.Client, containerID string, cmd []string) (string, error) {
@@ -33,6 +37,10 @@ func ExecInContainer(ctx context.Context, cli *client.Client, containerID string
 		return "", err
 	}
 	defer resp.Close()
+	// The attached connection ignores ctx, so close it to stop waiting once ctx is done
+	// (the command itself keeps running in the container)
+	stop := context.AfterFunc(ctx, resp.Close)
+	defer stop()
 
 	outputBuilder := &strings.Builder{}
 	buf := make([]byte, 1024)
@@ -45,10 +53,53 @@ func ExecInContainer(ctx context.Context, cli *client.Client, containerID string
 			break
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return outputBuilder.String(), err
+	}
 
 	return outputBuilder.String(), nil
 }
 
+// ExecInContainerStream runs a command in a container and copies its stdout to the given writer
+// as it is produced, without buffering it in memory. Stderr is collected and returned.
+// Returns an error if the command exits with a non-zero status.
+func ExecInContainerStream(ctx context.Context, cli *client.Client, containerID string, cmd []string, stdout io.Writer) (string, error) {
+	options := container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	execIDResp, err := cli.ContainerExecCreate(ctx, containerID, options)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := cli.ContainerExecAttach(ctx, execIDResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Close()
+	stop := context.AfterFunc(ctx, resp.Close)
+	defer stop()
+
+	var stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(stdout, &stderr, resp.Reader); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return stderr.String(), ctxErr
+		}
+		return stderr.String(), fmt.Errorf("stream exec output: %w", err)
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, execIDResp.ID)
+	if err != nil {
+		return stderr.String(), fmt.Errorf("inspect exec: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return stderr.String(), fmt.Errorf("command exited with code %d: %s", inspect.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+	return stderr.String(), nil
+}
+
 func CopyFileFromContainer(ctx context.Context, cli *client.Client, containerID, pathInContainer, hostDir string, onProgress func(expected, written int64)) (string, error) {
 	reader, stat, err := cli.CopyFromContainer(ctx, containerID, pathInContainer)
 	if err != nil {
@@ -99,6 +150,14 @@ func StartContainer(ctx context.Context, cli *client.Client, containerID string)
 	return cli.ContainerStart(ctx, containerID, container.StartOptions{})
 }
 
+func PauseContainer(ctx context.Context, cli *client.Client, containerID string) error {
+	return cli.ContainerPause(ctx, containerID)
+}
+
+func UnpauseContainer(ctx context.Context, cli *client.Client, containerID string) error {
+	return cli.ContainerUnpause(ctx, containerID)
+}
+
 func IsContainerRunning(ctx context.Context, cli *client.Client, containerID string) (bool, error) {
 	ctrJSON, err := cli.ContainerInspect(ctx, containerID)
 	if err != nil {
@@ -113,7 +172,7 @@ func IsContainerRunning(ctx context.Context, cli *client.Client, containerID str
 // hostBackupPath is the actual path on the host that /backup is mounted from.
 // Returns the paths to the staged data.
 // NOTE: Caller is responsible for cleaning up the staging directory after backup completes.
-func CopyVolumeToStaging(ctx context.Context, cli *client.Client, hostBackupPath, instanceID, timestamp, volumeName string, paths []string, logger *logging.JobLogger) ([]string, error) {
+func CopyVolumeToStaging(ctx context.Context, cli *client.Client, helper model.HelperSettings, hostBackupPath, instanceID, timestamp, volumeName string, paths []string, logger *logging.JobLogger) ([]string, error) {
 	// Create a unique subdirectory in staging for this volume backup
 	stagingSubdir := fmt.Sprintf("%s/%s/volume/%s", instanceID, timestamp, volumeName)
 	stagingPath := filepath.Join("/backup", stagingSubdir)
@@ -123,42 +182,31 @@ func CopyVolumeToStaging(ctx context.Context, cli *client.Client, hostBackupPath
 		return nil, fmt.Errorf("create staging dir: %w", err)
 	}
 
-	// Start temporary alpine container with both volumes mounted
+	// Start temporary helper container with both volumes mounted
 	config := &container.Config{
-		Image: "alpine:3.20",
+		Image: helper.Image,
 		Cmd:   []string{"sh", "-c", "sleep 300"}, // Keep container alive
 	}
 
-	// ensure config.Image is available locally
-	_, inspectErr := cli.ImageInspect(ctx, config.Image)
-	if inspectErr != nil {
-		rc, err := cli.ImagePull(ctx, config.Image, image.PullOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("pull alpine image: %w", err)
-		}
-		defer rc.Close()
-		if _, err := io.Copy(io.Discard, rc); err != nil {
-			return nil, fmt.Errorf("read image pull response: %w", err)
-		}
-	}
-
-	hostConfig := &container.HostConfig{
-		Mounts: []mount.Mount{
-			{
-				Type:     mount.TypeVolume,
-				Source:   volumeName,
-				Target:   "/source",
-				ReadOnly: true,
-			},
-			{
-				Type:   mount.TypeBind,
-				Source: hostBackupPath,
-				Target: "/backup",
-			},
-		},
-		AutoRemove: true,
+	if err := ensureImage(ctx, cli, config.Image); err != nil {
+		return nil, err
 	}
 
+	hostConfig := helperHostConfig(helper, []mount.Mount{
+		{
+			Type:     mount.TypeVolume,
+			Source:   volumeName,
+			Target:   "/source",
+			ReadOnly: true,
+		},
+		{
+			Type:   mount.TypeBind,
+			Source: hostBackupPath,
+			Target: "/backup",
+		},
+	})
+	hostConfig.AutoRemove = true
+
 	containerName := fmt.Sprintf("marina-copy-%d", time.Now().UnixNano())
 	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, containerName)
 	if err != nil {
@@ -167,10 +215,10 @@ func CopyVolumeToStaging(ctx context.Context, cli *client.Client, hostBackupPath
 	containerID := resp.ID
 	logger.Debug("started copy container %s for volume %s", containerName, volumeName)
 
-	// Ensure cleanup even if something goes wrong
+	// Ensure cleanup even if something goes wrong or ctx is cancelled
 	defer func() {
 		timeout := 2
-		_ = cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
+		_ = cli.ContainerStop(context.WithoutCancel(ctx), containerID, container.StopOptions{Timeout: &timeout})
 	}()
 
 	if err := cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
@@ -215,19 +263,150 @@ func CopyVolumeToStaging(ctx context.Context, cli *client.Client, hostBackupPath
 	return stagedPaths, nil
 }
 
-// GetBackupHostPath inspects Marina's own container to find the actual host path
-// for the /backup mount. This is needed to create bind mounts in temporary containers.
-func GetBackupHostPath(ctx context.Context, cli *client.Client) (string, error) {
+// EstimateVolumeSize measures the disk usage of the given paths inside a volume with 'du'
+// in a temporary helper container. Returns the total size in bytes.
+func EstimateVolumeSize(ctx context.Context, cli *client.Client, helper model.HelperSettings, volumeName string, paths []string) (int64, error) {
+	cmd := []string{"du", "-sk"}
+	for _, path := range paths {
+		cmd = append(cmd, filepath.Join("/source", strings.TrimPrefix(path, "/")))
+	}
+	config := &container.Config{
+		Image: helper.Image,
+		Cmd:   cmd,
+	}
+	if err := ensureImage(ctx, cli, config.Image); err != nil {
+		return 0, err
+	}
+
+	hostConfig := helperHostConfig(helper, []mount.Mount{
+		{
+			Type:     mount.TypeVolume,
+			Source:   volumeName,
+			Target:   "/source",
+			ReadOnly: true,
+		},
+	})
+
+	containerName := fmt.Sprintf("marina-du-%d", time.Now().UnixNano())
+	output, exitCode, err := RunToCompletion(ctx, cli, containerName, config, hostConfig)
+	if err != nil {
+		return 0, err
+	}
+	if exitCode != 0 {
+		return 0, fmt.Errorf("du exited with code %d: %s", exitCode, strings.TrimSpace(output))
+	}
+
+	// Output has one "<kilobytes>\t<path>" line per path
+	var total int64
+	for line := range strings.Lines(output) {
+		field, _, _ := strings.Cut(strings.TrimSpace(line), "\t")
+		kb, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse du output %q: %w", line, err)
+		}
+		total += kb * 1024
+	}
+	return total, nil
+}
+
+// ensureImage pulls an image unless it is already available locally
+func ensureImage(ctx context.Context, cli *client.Client, imageName string) error {
+	if _, err := cli.ImageInspect(ctx, imageName); err == nil {
+		return nil
+	}
+	rc, err := cli.ImagePull(ctx, imageName, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("pull image %s: %w", imageName, err)
+	}
+	defer rc.Close()
+	if _, err := io.Copy(io.Discard, rc); err != nil {
+		return fmt.Errorf("read image pull response: %w", err)
+	}
+	return nil
+}
+
+// InspectSelf inspects Marina's own container (identified by its hostname)
+func InspectSelf(ctx context.Context, cli *client.Client) (container.InspectResponse, error) {
 	// Get Marina's container ID from hostname
 	hostname, err := os.Hostname()
 	if err != nil {
-		return "", fmt.Errorf("get hostname: %w", err)
+		return container.InspectResponse{}, fmt.Errorf("get hostname: %w", err)
 	}
 
-	// Inspect Marina's container
 	inspect, err := cli.ContainerInspect(ctx, hostname)
 	if err != nil {
-		return "", fmt.Errorf("inspect marina container: %w", err)
+		return container.InspectResponse{}, fmt.Errorf("inspect marina container: %w", err)
+	}
+	return inspect, nil
+}
+
+// RunToCompletion creates and starts a container, waits for it to exit and returns its combined
+// output and exit code. The container is always removed, even if ctx is cancelled.
+func RunToCompletion(ctx context.Context, cli *client.Client, containerName string, config *container.Config, hostConfig *container.HostConfig) (string, int64, error) {
+	return RunToCompletionWithFiles(ctx, cli, containerName, config, hostConfig, nil)
+}
+
+// RunToCompletionWithFiles is RunToCompletion with files (absolute path -> content) copied into the
+// container before it starts, owned by root and readable only by it. Used for secrets that must not show up in
+// the container's Env, where 'docker inspect' would reveal them.
+func RunToCompletionWithFiles(ctx context.Context, cli *client.Client, containerName string, config *container.Config, hostConfig *container.HostConfig, files map[string][]byte) (string, int64, error) {
+	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, containerName)
+	if err != nil {
+		return "", 0, fmt.Errorf("create container %s: %w", containerName, err)
+	}
+	containerID := resp.ID
+	defer func() {
+		_ = cli.ContainerRemove(context.WithoutCancel(ctx), containerID, container.RemoveOptions{Force: true})
+	}()
+
+	for path, content := range files {
+		var archive bytes.Buffer
+		tw := tar.NewWriter(&archive)
+		if err := tw.WriteHeader(&tar.Header{Name: filepath.Base(path), Mode: 0o400, Size: int64(len(content))}); err != nil {
+			return "", 0, fmt.Errorf("copy %s into container %s: %w", path, containerName, err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			return "", 0, fmt.Errorf("copy %s into container %s: %w", path, containerName, err)
+		}
+		if err := tw.Close(); err != nil {
+			return "", 0, fmt.Errorf("copy %s into container %s: %w", path, containerName, err)
+		}
+		if err := cli.CopyToContainer(ctx, containerID, filepath.Dir(path), &archive, container.CopyToContainerOptions{}); err != nil {
+			return "", 0, fmt.Errorf("copy %s into container %s: %w", path, containerName, err)
+		}
+	}
+
+	if err := cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		return "", 0, fmt.Errorf("start container %s: %w", containerName, err)
+	}
+
+	statusCh, errCh := cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	var exitCode int64
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return "", 0, fmt.Errorf("wait for container %s: %w", containerName, err)
+		}
+	case status := <-statusCh:
+		exitCode = status.StatusCode
+	}
+
+	var output bytes.Buffer
+	logs, err := cli.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err == nil {
+		_, _ = stdcopy.StdCopy(&output, &output, logs)
+		logs.Close()
+	}
+
+	return output.String(), exitCode, nil
+}
+
+// GetBackupHostPath inspects Marina's own container to find the actual host path
+// for the /backup mount. This is needed to create bind mounts in temporary containers.
+func GetBackupHostPath(ctx context.Context, cli *client.Client) (string, error) {
+	inspect, err := InspectSelf(ctx, cli)
+	if err != nil {
+		return "", err
 	}
 
 	// Find the mount for /backup
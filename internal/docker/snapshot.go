@@ -0,0 +1,244 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// snapshotDriver builds the host-side shell scripts that create and destroy a read-only
+// filesystem snapshot. Scripts run in the host's mount namespace, so all paths are host paths.
+type snapshotDriver interface {
+	// createScript snapshots the filesystem holding sourcePath and bind-mounts the snapshotted
+	// copy of sourcePath read-only at mountPath
+	createScript(sourcePath, mountPath, name string) string
+	// destroyScript unmounts mountPath and destroys the snapshot created by createScript
+	destroyScript(sourcePath, mountPath, name string) string
+}
+
+// snapshotDrivers maps the configured snapshot driver name to its implementation.
+// Register additional filesystems here.
+var snapshotDrivers = map[string]snapshotDriver{
+	model.SnapshotBtrfs: btrfsSnapshot{},
+	model.SnapshotZFS:   zfsSnapshot{},
+	model.SnapshotLVM:   lvmSnapshot{},
+}
+
+// bindReadOnly bind-mounts $snap$rel read-only at the given mount path.
+// The preludes keep the leading slash of rel, also when the filesystem is mounted at /
+// or the volume directory is the snapshotted root itself.
+func bindReadOnly(mountPath string) string {
+	return fmt.Sprintf(`mount --bind "$snap$rel" %s
+mount -o remount,bind,ro %s
+`, shellQuote(mountPath), shellQuote(mountPath))
+}
+
+// btrfsSnapshot snapshots the btrfs subvolume that contains the volume.
+// If the volume directory itself is a subvolume it is snapshotted directly; otherwise the
+// subvolume mounted at the containing mountpoint is used. Nested subvolumes are not included.
+type btrfsSnapshot struct{}
+
+func (btrfsSnapshot) prelude(sourcePath, name string) string {
+	return fmt.Sprintf(`set -e
+src=%s
+if btrfs subvolume show "$src" >/dev/null 2>&1; then base="$src"; else base=$(findmnt -n -o TARGET --target "$src"); fi
+rel=${src#"$base"}
+rel="/${rel#/}"
+snap="${base%%/}/.marina-snapshots/%s"
+`, shellQuote(sourcePath), name)
+}
+
+func (d btrfsSnapshot) createScript(sourcePath, mountPath, name string) string {
+	return d.prelude(sourcePath, name) + `mkdir -p "${base%/}/.marina-snapshots"
+btrfs subvolume snapshot -r "$base" "$snap"
+` + bindReadOnly(mountPath)
+}
+
+func (d btrfsSnapshot) destroyScript(sourcePath, mountPath, name string) string {
+	return d.prelude(sourcePath, name) + fmt.Sprintf(`umount %s 2>/dev/null || true
+btrfs subvolume delete "$snap"
+`, shellQuote(mountPath))
+}
+
+// zfsSnapshot snapshots the ZFS dataset that contains the volume and exposes it
+// through the dataset's .zfs/snapshot directory.
+type zfsSnapshot struct{}
+
+func (zfsSnapshot) prelude(sourcePath, name string) string {
+	return fmt.Sprintf(`set -e
+src=%s
+ds=$(zfs list -H -o name "$src")
+base=$(zfs get -H -o value mountpoint "$ds")
+rel=${src#"$base"}
+rel="/${rel#/}"
+snap="${base%%/}/.zfs/snapshot/%s"
+`, shellQuote(sourcePath), name)
+}
+
+func (d zfsSnapshot) createScript(sourcePath, mountPath, name string) string {
+	return d.prelude(sourcePath, name) + fmt.Sprintf(`zfs snapshot "$ds@%s"
+`, name) + bindReadOnly(mountPath)
+}
+
+func (d zfsSnapshot) destroyScript(sourcePath, mountPath, name string) string {
+	return d.prelude(sourcePath, name) + fmt.Sprintf(`umount %s 2>/dev/null || true
+zfs destroy "$ds@%s"
+`, shellQuote(mountPath), name)
+}
+
+// lvmSnapshot creates a thin snapshot of the LVM logical volume that contains the volume
+// and mounts it read-only below /run/marina-snapshots on the host.
+type lvmSnapshot struct{}
+
+func (lvmSnapshot) prelude(sourcePath, name string) string {
+	return fmt.Sprintf(`set -e
+src=%s
+dev=$(findmnt -n -o SOURCE --target "$src")
+base=$(findmnt -n -o TARGET --target "$src")
+fstype=$(findmnt -n -o FSTYPE --target "$src")
+rel=${src#"$base"}
+rel="/${rel#/}"
+vglv=$(lvs --noheadings -o vg_name,lv_name "$dev" | awk '{print $1"/"$2}')
+vg=${vglv%%/*}
+snap=/run/marina-snapshots/%s
+`, shellQuote(sourcePath), name)
+}
+
+func (d lvmSnapshot) createScript(sourcePath, mountPath, name string) string {
+	return d.prelude(sourcePath, name) + fmt.Sprintf(`lvcreate -s -n %s "$vglv"
+lvchange -ay -K "$vg/%s"
+mkdir -p "$snap"
+case "$fstype" in
+  xfs) opts=ro,nouuid,norecovery ;;
+  ext3|ext4) opts=ro,noload ;;
+  *) opts=ro ;;
+esac
+mount -o "$opts" "/dev/$vg/%s" "$snap"
+`, name, name, name) + bindReadOnly(mountPath)
+}
+
+func (d lvmSnapshot) destroyScript(sourcePath, mountPath, name string) string {
+	return d.prelude(sourcePath, name) + fmt.Sprintf(`umount %s 2>/dev/null || true
+umount "$snap" 2>/dev/null || true
+rmdir "$snap" 2>/dev/null || true
+lvremove -f "$vg/%s"
+`, shellQuote(mountPath), name)
+}
+
+// snapshotNameInvalidChars matches characters not allowed in btrfs/ZFS/LVM snapshot names
+var snapshotNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// SnapshotVolumeToStaging takes a filesystem snapshot of a volume's host mountpoint and mounts it
+// read-only into the staging directory, so the backend can read a consistent point-in-time view
+// without copying any data. Snapshot commands run on the host via a privileged helper container.
+// Requires /backup to be mounted into Marina with rslave (or rshared) propagation so the
+// snapshot mount becomes visible inside Marina's container.
+// Returns the staged paths and a release function that unmounts and destroys the snapshot.
+// The release function must be called before the staging directory is removed.
+func SnapshotVolumeToStaging(ctx context.Context, cli *client.Client, helperImage, hostBackupPath, instanceID, timestamp, volumeName, volumeMountpoint, driverName string, paths []string, logger *logging.JobLogger) ([]string, func(), error) {
+	driver, ok := snapshotDrivers[driverName]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported snapshot driver %q", driverName)
+	}
+	if volumeMountpoint == "" {
+		return nil, nil, fmt.Errorf("volume %s has no host mountpoint (only local volumes can be snapshotted)", volumeName)
+	}
+
+	stagingSubdir := fmt.Sprintf("%s/%s/volume/%s", instanceID, timestamp, volumeName)
+	stagingPath := filepath.Join("/backup", stagingSubdir)
+	hostMountPath := filepath.Join(hostBackupPath, stagingSubdir)
+
+	// Create the (empty) mount point; the snapshot is mounted over it on the host
+	if err := os.MkdirAll(stagingPath, 0755); err != nil {
+		return nil, nil, fmt.Errorf("create staging dir: %w", err)
+	}
+
+	name := snapshotNameInvalidChars.ReplaceAllString(fmt.Sprintf("marina-%s-%s-%s", instanceID, timestamp, volumeName), "_")
+
+	logger.Debug("creating %s snapshot %s of %s", driverName, name, volumeMountpoint)
+	output, err := RunOnHost(ctx, cli, helperImage, driver.createScript(volumeMountpoint, hostMountPath, name))
+	if output != "" {
+		logger.Debug("snapshot output: %s", output)
+	}
+
+	release := func() {
+		logger.Debug("destroying %s snapshot %s", driverName, name)
+		output, err := RunOnHost(context.WithoutCancel(ctx), cli, helperImage, driver.destroyScript(volumeMountpoint, hostMountPath, name))
+		if err != nil {
+			logger.Warn("failed to destroy snapshot %s: %v (%s)", name, err, output)
+		}
+	}
+
+	if err != nil {
+		// The script may have failed half way (e.g. after creating the snapshot) - clean up
+		release()
+		return nil, nil, fmt.Errorf("create %s snapshot: %w", driverName, err)
+	}
+
+	// Make sure the host mount propagated into Marina's view of /backup
+	entries, err := os.ReadDir(stagingPath)
+	if err != nil || len(entries) == 0 {
+		release()
+		return nil, nil, fmt.Errorf("snapshot mount is not visible at %s: mount /backup with rslave propagation", stagingPath)
+	}
+
+	stagedPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		cleanPath := strings.TrimPrefix(path, "/")
+		if cleanPath == "" {
+			cleanPath = "."
+		}
+		stagedPath := filepath.Join(stagingPath, cleanPath)
+		if _, err := os.Stat(stagedPath); err != nil {
+			release()
+			return nil, nil, fmt.Errorf("path %s not found in snapshot: %w", path, err)
+		}
+		stagedPaths = append(stagedPaths, stagedPath)
+	}
+
+	return stagedPaths, release, nil
+}
+
+// RunOnHost runs a shell script in the host's mount namespace using a short-lived privileged
+// helper container (nsenter into PID 1) using the given helper image, which must provide nsenter.
+// The host must provide the tools the script uses.
+// Returns the combined output of the script.
+func RunOnHost(ctx context.Context, cli *client.Client, helperImage, script string) (string, error) {
+	config := &container.Config{
+		Image: helperImage,
+		Cmd:   []string{"nsenter", "-t", "1", "-m", "--", "sh", "-c", script},
+	}
+	if err := ensureImage(ctx, cli, config.Image); err != nil {
+		return "", err
+	}
+
+	hostConfig := &container.HostConfig{
+		Privileged: true,
+		PidMode:    "host",
+	}
+
+	containerName := fmt.Sprintf("marina-host-%d", time.Now().UnixNano())
+	output, exitCode, err := RunToCompletion(ctx, cli, containerName, config, hostConfig)
+	if err != nil {
+		return "", err
+	}
+	if exitCode != 0 {
+		return output, fmt.Errorf("host command exited with code %d: %s", exitCode, strings.TrimSpace(output))
+	}
+	return output, nil
+}
+
+// shellQuote quotes a string for safe use in a POSIX shell script
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
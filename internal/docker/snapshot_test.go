@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSnapshotTools is installed on PATH under the names of the host tools the snapshot scripts
+// call. Queries answer from FAKE_* variables, everything else is appended to $FAKE_LOG.
+const fakeSnapshotTools = `#!/bin/sh
+case "$(basename "$0") $*" in
+  "btrfs subvolume show"*) [ "$FAKE_SUBVOLUME" = 1 ]; exit ;;
+  "findmnt -n -o TARGET"*) echo "$FAKE_BASE" ;;
+  "findmnt -n -o SOURCE"*) echo /dev/mapper/vg0-data ;;
+  "findmnt -n -o FSTYPE"*) echo ext4 ;;
+  "zfs list"*) echo tank/data ;;
+  "zfs get"*) echo "$FAKE_BASE" ;;
+  "lvs "*) echo "  vg0 data" ;;
+  *) echo "$(basename "$0") $*" >> "$FAKE_LOG" ;;
+esac
+`
+
+func TestSnapshotCreateScript_BindSource(t *testing.T) {
+	const src = "/var/lib/docker/volumes/app/_data"
+	const mountPath = "/srv/backup/inst/20250101/volume/app"
+
+	tests := []struct {
+		name      string
+		driver    snapshotDriver
+		base      string
+		subvolume bool
+		wantBind  string
+	}{
+		{name: "btrfs below mountpoint", driver: btrfsSnapshot{}, base: "/var/lib/docker", wantBind: "/var/lib/docker/.marina-snapshots/snap/volumes/app/_data"},
+		{name: "btrfs on root filesystem", driver: btrfsSnapshot{}, base: "/", wantBind: "/.marina-snapshots/snap/var/lib/docker/volumes/app/_data"},
+		{name: "btrfs volume is subvolume", driver: btrfsSnapshot{}, subvolume: true, wantBind: src + "/.marina-snapshots/snap/"},
+		{name: "zfs below mountpoint", driver: zfsSnapshot{}, base: "/var/lib/docker", wantBind: "/var/lib/docker/.zfs/snapshot/snap/volumes/app/_data"},
+		{name: "zfs on root dataset", driver: zfsSnapshot{}, base: "/", wantBind: "/.zfs/snapshot/snap/var/lib/docker/volumes/app/_data"},
+		{name: "zfs volume is dataset", driver: zfsSnapshot{}, base: src, wantBind: src + "/.zfs/snapshot/snap/"},
+		{name: "lvm below mountpoint", driver: lvmSnapshot{}, base: "/var/lib/docker", wantBind: "/run/marina-snapshots/snap/volumes/app/_data"},
+		{name: "lvm on root filesystem", driver: lvmSnapshot{}, base: "/", wantBind: "/run/marina-snapshots/snap/var/lib/docker/volumes/app/_data"},
+		{name: "lvm volume is mountpoint", driver: lvmSnapshot{}, base: src, wantBind: "/run/marina-snapshots/snap/"},
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "fake"), []byte(fakeSnapshotTools), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range []string{"btrfs", "findmnt", "zfs", "lvs", "lvcreate", "lvchange", "mkdir", "mount"} {
+		if err := os.Symlink(filepath.Join(bin, "fake"), filepath.Join(bin, tool)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := filepath.Join(t.TempDir(), "log")
+			subvolume := "0"
+			if tt.subvolume {
+				subvolume = "1"
+			}
+			cmd := exec.Command("/bin/sh", "-c", tt.driver.createScript(src, mountPath, "snap"))
+			cmd.Env = []string{
+				"PATH=" + bin + ":" + os.Getenv("PATH"),
+				"FAKE_LOG=" + log,
+				"FAKE_BASE=" + tt.base,
+				"FAKE_SUBVOLUME=" + subvolume,
+			}
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("script failed: %v\n%s", err, out)
+			}
+			calls, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "mount --bind " + tt.wantBind + " " + mountPath
+			if !strings.Contains(string(calls), want+"\n") {
+				t.Errorf("script did not run %q, calls:\n%s", want, calls)
+			}
+		})
+	}
+}
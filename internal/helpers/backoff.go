@@ -0,0 +1,25 @@
+package helpers
+
+import (
+	"math"
+	"time"
+)
+
+// MaxBackoff caps the delay returned by BackoffDelay
+const MaxBackoff = 6 * time.Hour
+
+// BackoffDelay returns how long to wait after the given failed attempt (1-based) with exponential
+// backoff: initial * multiplier^(attempt-1), capped at MaxBackoff
+func BackoffDelay(initial time.Duration, multiplier float64, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if delay > float64(MaxBackoff) {
+		return MaxBackoff
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	cases := []struct {
+		initial    time.Duration
+		multiplier float64
+		attempt    int
+		want       time.Duration
+	}{
+		{30 * time.Second, 2, 1, 30 * time.Second},
+		{30 * time.Second, 2, 2, time.Minute},
+		{30 * time.Second, 2, 4, 4 * time.Minute},
+		{time.Minute, 1.5, 3, 135 * time.Second},
+		{time.Minute, 1, 5, time.Minute},
+		{time.Minute, 0.5, 3, time.Minute}, // multiplier below 1 is treated as 1
+		{time.Minute, 2, 0, time.Minute},   // attempt below 1 is treated as 1
+		{time.Hour, 10, 20, MaxBackoff},
+	}
+	for _, c := range cases {
+		if got := BackoffDelay(c.initial, c.multiplier, c.attempt); got != c.want {
+			t.Errorf("BackoffDelay(%s, %v, %d) = %s, want %s", c.initial, c.multiplier, c.attempt, got, c.want)
+		}
+	}
+}
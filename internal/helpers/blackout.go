@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"slices"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// maxBlackoutChain bounds the search through adjoining windows and skipped ticks
+const maxBlackoutChain = 1000
+
+// BlackoutAt reports whether t falls into one of the blackout windows. If it does, it returns the
+// time the windows end (adjoining and overlapping windows are merged) and the policy that applies:
+// skip if any window covering t skips runs, defer otherwise.
+func BlackoutAt(windows []model.BlackoutWindow, t time.Time) (time.Time, model.BlackoutPolicy, bool) {
+	end := t
+	for range maxBlackoutChain {
+		at := end
+		for _, window := range windows {
+			windowEnd, ok := windowEndAt(window, at)
+			if !ok {
+				continue
+			}
+			if at.Equal(t) && window.Policy == model.BlackoutSkip {
+				return windowEnd, model.BlackoutSkip, true
+			}
+			if windowEnd.After(end) {
+				end = windowEnd
+			}
+		}
+		if end.Equal(at) {
+			break
+		}
+	}
+	if end.Equal(t) {
+		return time.Time{}, "", false
+	}
+	return end, model.BlackoutDefer, true
+}
+
+// NextAllowedRun returns when the next run of a schedule starting from its next tick actually starts:
+// the tick itself, the end of a deferring blackout window, or a later tick if windows skip it.
+// Returns the zero time if no allowed run was found.
+func NextAllowedRun(schedule cron.Schedule, windows []model.BlackoutWindow, next time.Time) time.Time {
+	for range maxBlackoutChain {
+		if next.IsZero() {
+			return next
+		}
+		end, policy, active := BlackoutAt(windows, next)
+		if !active {
+			return next
+		}
+		if policy == model.BlackoutDefer {
+			return end
+		}
+		next = schedule.Next(next)
+	}
+	return time.Time{}
+}
+
+// windowEndAt returns the end of the window occurrence that contains t, if any
+func windowEndAt(window model.BlackoutWindow, t time.Time) (time.Time, bool) {
+	if window.Cron != "" {
+		schedule, err := CronParser.Parse(CronSpec(window.Cron, window.Timezone))
+		if err != nil {
+			return time.Time{}, false
+		}
+		// Occurrences containing t opened in [t-Duration, t]; a later one keeps the window open longer
+		var end time.Time
+		for open := schedule.Next(t.Add(-window.Duration - time.Nanosecond)); !open.After(t); open = schedule.Next(open) {
+			end = open.Add(window.Duration)
+		}
+		return end, end.After(t)
+	}
+
+	if window.Timezone != "" {
+		if loc, err := time.LoadLocation(window.Timezone); err == nil {
+			t = t.In(loc)
+		}
+	}
+	startMinutes := int(window.Start.Minutes())
+	endMinutes := int(window.End.Minutes())
+	if endMinutes <= startMinutes {
+		endMinutes += 24 * 60
+	}
+	// An occurrence containing t opened today or yesterday (wall clock, so DST changes are handled)
+	for _, daysBack := range []int{0, 1} {
+		day := t.Day() - daysBack
+		open := time.Date(t.Year(), t.Month(), day, 0, startMinutes, 0, 0, t.Location())
+		if len(window.Days) > 0 && !slices.Contains(window.Days, open.Weekday()) {
+			continue
+		}
+		end := time.Date(t.Year(), t.Month(), day, 0, endMinutes, 0, 0, t.Location())
+		if !t.Before(open) && t.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
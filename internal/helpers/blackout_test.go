@@ -0,0 +1,79 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+func TestBlackoutAt(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, time.UTC) // 2025-01-06 is a Monday
+	}
+	nightly := model.BlackoutWindow{Start: 22 * time.Hour, End: 2 * time.Hour, Timezone: "UTC", Policy: model.BlackoutDefer}
+	releases := model.BlackoutWindow{Days: []time.Weekday{time.Tuesday}, Start: 9 * time.Hour, End: 12 * time.Hour, Timezone: "UTC", Policy: model.BlackoutSkip}
+	fridays := model.BlackoutWindow{Cron: "0 18 * * 5", Duration: 3 * time.Hour, Timezone: "UTC", Policy: model.BlackoutDefer}
+	adjoining := model.BlackoutWindow{Start: 2 * time.Hour, End: 4 * time.Hour, Timezone: "UTC", Policy: model.BlackoutDefer}
+
+	tests := []struct {
+		name           string
+		windows        []model.BlackoutWindow
+		t              time.Time
+		expectedActive bool
+		expectedEnd    time.Time
+		expectedPolicy model.BlackoutPolicy
+	}{
+		{name: "no windows", t: at(6, 23, 0)},
+		{name: "before range", windows: []model.BlackoutWindow{nightly}, t: at(6, 21, 59)},
+		{name: "in range", windows: []model.BlackoutWindow{nightly}, t: at(6, 23, 0), expectedActive: true, expectedEnd: at(7, 2, 0), expectedPolicy: model.BlackoutDefer},
+		{name: "after midnight", windows: []model.BlackoutWindow{nightly}, t: at(7, 1, 0), expectedActive: true, expectedEnd: at(7, 2, 0), expectedPolicy: model.BlackoutDefer},
+		{name: "at range end", windows: []model.BlackoutWindow{nightly}, t: at(7, 2, 0)},
+		{name: "weekday match", windows: []model.BlackoutWindow{releases}, t: at(7, 10, 0), expectedActive: true, expectedEnd: at(7, 12, 0), expectedPolicy: model.BlackoutSkip},
+		{name: "other weekday", windows: []model.BlackoutWindow{releases}, t: at(8, 10, 0)},
+		{name: "cron window", windows: []model.BlackoutWindow{fridays}, t: at(10, 20, 30), expectedActive: true, expectedEnd: at(10, 21, 0), expectedPolicy: model.BlackoutDefer},
+		{name: "outside cron window", windows: []model.BlackoutWindow{fridays}, t: at(10, 21, 0)},
+		{name: "adjoining windows merge", windows: []model.BlackoutWindow{nightly, adjoining}, t: at(6, 23, 0), expectedActive: true, expectedEnd: at(7, 4, 0), expectedPolicy: model.BlackoutDefer},
+		{name: "skip wins", windows: []model.BlackoutWindow{nightly, {Start: 23 * time.Hour, End: 23*time.Hour + 30*time.Minute, Timezone: "UTC", Policy: model.BlackoutSkip}}, t: at(6, 23, 10), expectedActive: true, expectedEnd: at(6, 23, 30), expectedPolicy: model.BlackoutSkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, policy, active := BlackoutAt(tt.windows, tt.t)
+			if active != tt.expectedActive {
+				t.Fatalf("expected active %v, got %v", tt.expectedActive, active)
+			}
+			if !active {
+				return
+			}
+			if !end.Equal(tt.expectedEnd) {
+				t.Errorf("expected end %v, got %v", tt.expectedEnd, end)
+			}
+			if policy != tt.expectedPolicy {
+				t.Errorf("expected policy %q, got %q", tt.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func TestNextAllowedRun(t *testing.T) {
+	schedule, err := CronParser.Parse("CRON_TZ=UTC 0 * * * *") // hourly
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tick := time.Date(2025, 1, 6, 23, 0, 0, 0, time.UTC)
+
+	deferring := []model.BlackoutWindow{{Start: 22 * time.Hour, End: 2 * time.Hour, Timezone: "UTC", Policy: model.BlackoutDefer}}
+	if got := NextAllowedRun(schedule, deferring, tick); !got.Equal(time.Date(2025, 1, 7, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected run deferred to window end, got %v", got)
+	}
+
+	skipping := []model.BlackoutWindow{{Start: 22 * time.Hour, End: 2*time.Hour + 30*time.Minute, Timezone: "UTC", Policy: model.BlackoutSkip}}
+	if got := NextAllowedRun(schedule, skipping, tick); !got.Equal(time.Date(2025, 1, 7, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected first tick after the window, got %v", got)
+	}
+
+	if got := NextAllowedRun(schedule, nil, tick); !got.Equal(tick) {
+		t.Errorf("expected tick unchanged without windows, got %v", got)
+	}
+}
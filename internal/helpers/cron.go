@@ -3,11 +3,27 @@ package helpers
 import (
 	"fmt"
 	"strings"
+
+	"github.com/robfig/cron/v3"
 )
 
+// CronParser parses backup schedules: standard 5-field expressions with an optional leading
+// seconds field, descriptors such as @daily and @every 6h, and an optional CRON_TZ= prefix
+var CronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ValidateCron checks a schedule with the parser used by the scheduler
 func ValidateCron(c string) error {
-	if strings.Count(c, " ") < 4 {
-		return fmt.Errorf("cron expression too short: %q", c)
+	if _, err := CronParser.Parse(c); err != nil {
+		return fmt.Errorf("invalid cron expression %q: %w", c, err)
 	}
 	return nil
 }
+
+// CronSpec returns the schedule evaluated in the given IANA time zone ("" = local time).
+// Schedules that already carry a CRON_TZ= or TZ= prefix are returned unchanged.
+func CronSpec(schedule, timezone string) string {
+	if timezone == "" || strings.HasPrefix(schedule, "CRON_TZ=") || strings.HasPrefix(schedule, "TZ=") {
+		return schedule
+	}
+	return "CRON_TZ=" + timezone + " " + schedule
+}
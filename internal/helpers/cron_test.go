@@ -1,12 +1,52 @@
 package helpers
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestValidateCron(t *testing.T) {
-	if err := ValidateCron("0 3 * * *"); err != nil {
-		t.Fatalf("expected valid cron, got error: %v", err)
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"0 3 * * *", false},
+		{"30 0 3 * * *", false}, // with seconds
+		{"@daily", false},
+		{"@every 6h", false},
+		{"CRON_TZ=Europe/Berlin 0 3 * * *", false},
+		{"0 3 * *", true},
+		{"0 25 * * *", true},
+		{"@every", true},
+		{"@fortnightly", true},
+		{"CRON_TZ=Mars/Olympus 0 3 * * *", true},
 	}
-	if err := ValidateCron("0 3 * *"); err == nil {
-		t.Fatalf("expected error for short cron, got nil")
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			err := ValidateCron(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCron(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronSpec(t *testing.T) {
+	if got := CronSpec("0 3 * * *", ""); got != "0 3 * * *" {
+		t.Errorf("expected schedule unchanged without time zone, got %q", got)
+	}
+	if got := CronSpec("CRON_TZ=UTC 0 3 * * *", "Europe/Berlin"); got != "CRON_TZ=UTC 0 3 * * *" {
+		t.Errorf("expected explicit CRON_TZ to win, got %q", got)
+	}
+
+	spec := CronSpec("0 3 * * *", "America/New_York")
+	schedule, err := CronParser.Parse(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC) // 03:00 EST
+	if next := schedule.Next(from); !next.Equal(expected) {
+		t.Errorf("expected next run %v, got %v", expected, next.UTC())
 	}
 }
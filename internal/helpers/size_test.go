@@ -0,0 +1,57 @@
+package helpers
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                      "0 B",
+		1023:                   "1023 B",
+		1024:                   "1.0 KiB",
+		1536:                   "1.5 KiB",
+		10 * 1024 * 1024:       "10.0 MiB",
+		3 * 1024 * 1024 * 1024: "3.0 GiB",
+	}
+	for in, exp := range cases {
+		if out := FormatBytes(in); out != exp {
+			t.Errorf("FormatBytes(%d) = %q, want %q", in, out, exp)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	valid := map[string]int64{
+		"1024": 1024,
+		"512b": 512,
+		"4k":   4096,
+		"256m": 256 * 1024 * 1024,
+		"1G":   1024 * 1024 * 1024,
+		"1.5g": 1536 * 1024 * 1024,
+		" 2M ": 2 * 1024 * 1024,
+	}
+	for in, exp := range valid {
+		out, err := ParseByteSize(in)
+		if err != nil {
+			t.Errorf("ParseByteSize(%q) unexpected error: %v", in, err)
+		} else if out != exp {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", in, out, exp)
+		}
+	}
+	for _, in := range []string{"", "m", "abc", "-1g", "10t"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q) expected error", in)
+		}
+	}
+}
+
+func TestFreeDiskSpace(t *testing.T) {
+	free, err := FreeDiskSpace(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if free <= 0 {
+		t.Errorf("expected free space > 0, got %d", free)
+	}
+	if _, err := FreeDiskSpace("/does/not/exist"); err == nil {
+		t.Errorf("expected error for missing path")
+	}
+}
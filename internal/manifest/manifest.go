@@ -0,0 +1,229 @@
+// Package manifest builds and verifies checksum manifests of staged backup data.
+// A manifest lists every staged file, directory and symlink with its size, mode and
+// SHA-256 checksum, so a restored tree can be proven identical to what was staged.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the manifest file inside the staging directory
+const FileName = "marina-manifest.json"
+
+// Entry describes a single staged filesystem entry
+type Entry struct {
+	Path    string `json:"path"`             // slash-separated path relative to the manifest root
+	Size    int64  `json:"size"`             // size in bytes (regular files only)
+	Mode    string `json:"mode"`             // file mode, e.g. "-rw-r--r--"
+	ModTime int64  `json:"mtime,omitempty"`  // modification time in Unix nanoseconds (regular files only)
+	SHA256  string `json:"sha256,omitempty"` // hex checksum (regular files only)
+	Link    string `json:"link,omitempty"`   // symlink target (symlinks only)
+}
+
+// Manifest is the list of staged entries of one backup run
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Paths     []string  `json:"paths"` // staged paths the entries were collected from, relative to the root
+	Entries   []Entry   `json:"entries"`
+	Reused    int       `json:"-"` // files whose checksum was taken from the previous manifest by Build
+}
+
+// Problem describes a difference between a manifest and a tree
+type Problem struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Reason
+}
+
+// Build creates a manifest of the given paths, which must be located below root.
+// Entries are recorded relative to root and sorted by path. Regular files whose path, size, mode
+// and modification time match an entry of previous (nil if there is none) keep its checksum
+// instead of being read again.
+func Build(root string, paths []string, previous *Manifest) (*Manifest, error) {
+	m := &Manifest{Version: 1, CreatedAt: time.Now().UTC(), Paths: []string{}, Entries: []Entry{}}
+	seen := make(map[string]bool)
+	known := make(map[string]Entry)
+	if previous != nil {
+		for _, entry := range previous.Entries {
+			if entry.SHA256 != "" && entry.ModTime != 0 {
+				known[entry.Path] = entry
+			}
+		}
+	}
+
+	for _, path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil, fmt.Errorf("path %s is not below %s", path, root)
+		}
+		m.Paths = append(m.Paths, filepath.ToSlash(rel))
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			entry, reused, err := describe(root, p, known)
+			if err != nil {
+				return err
+			}
+			if !seen[entry.Path] {
+				if reused {
+					m.Reused++
+				}
+				seen[entry.Path] = true
+				m.Entries = append(m.Entries, entry)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk %s: %w", path, err)
+		}
+	}
+
+	slices.SortFunc(m.Entries, func(a, b Entry) int { return strings.Compare(a.Path, b.Path) })
+	return m, nil
+}
+
+// describe creates the manifest entry of the file at path. A regular file that matches its entry
+// in known keeps the known checksum; reused reports whether it did.
+func describe(root, path string, known map[string]Entry) (entry Entry, reused bool, err error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	entry = Entry{Path: filepath.ToSlash(rel), Mode: info.Mode().String()}
+	switch {
+	case info.Mode().IsRegular():
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime().UnixNano()
+		if prev, ok := known[entry.Path]; ok && prev.Size == entry.Size && prev.Mode == entry.Mode && prev.ModTime == entry.ModTime {
+			entry.SHA256 = prev.SHA256
+			return entry, true, nil
+		}
+		entry.SHA256, err = hashFile(path)
+		if err != nil {
+			return Entry{}, false, err
+		}
+	case info.Mode()&fs.ModeSymlink != 0:
+		entry.Link, err = os.Readlink(path)
+		if err != nil {
+			return Entry{}, false, err
+		}
+	}
+	return entry, false, nil
+}
+
+// hashFile returns the hex SHA-256 checksum of a file
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Write stores the manifest as JSON at path
+func (m *Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// Load reads a manifest from a JSON file
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// Verify compares the tree below root against the manifest. It reports entries that are missing
+// or differ, and files below the staged paths that the manifest does not list.
+// An empty result means the tree matches.
+func (m *Manifest) Verify(root string) ([]Problem, error) {
+	var problems []Problem
+	expected := make(map[string]bool, len(m.Entries))
+
+	for _, want := range m.Entries {
+		expected[want.Path] = true
+
+		got, _, err := describe(root, filepath.Join(root, filepath.FromSlash(want.Path)), nil)
+		if err != nil {
+			if os.IsNotExist(err) {
+				problems = append(problems, Problem{Path: want.Path, Reason: "missing"})
+				continue
+			}
+			return nil, err
+		}
+		switch {
+		case got.Mode != want.Mode:
+			problems = append(problems, Problem{Path: want.Path, Reason: fmt.Sprintf("mode %s, expected %s", got.Mode, want.Mode)})
+		case got.Size != want.Size:
+			problems = append(problems, Problem{Path: want.Path, Reason: fmt.Sprintf("size %d, expected %d", got.Size, want.Size)})
+		case got.SHA256 != want.SHA256:
+			problems = append(problems, Problem{Path: want.Path, Reason: "checksum mismatch"})
+		case got.Link != want.Link:
+			problems = append(problems, Problem{Path: want.Path, Reason: fmt.Sprintf("links to %s, expected %s", got.Link, want.Link)})
+		}
+	}
+
+	// Look for entries that were not staged
+	for _, staged := range m.Paths {
+		err := filepath.WalkDir(filepath.Join(root, filepath.FromSlash(staged)), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil // already reported as missing
+				}
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			if !expected[filepath.ToSlash(rel)] {
+				problems = append(problems, Problem{Path: filepath.ToSlash(rel), Reason: "not in manifest"})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk %s: %w", staged, err)
+		}
+	}
+
+	slices.SortFunc(problems, func(a, b Problem) int { return strings.Compare(a.Path, b.Path) })
+	return problems, nil
+}
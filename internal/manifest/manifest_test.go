@@ -0,0 +1,235 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// stage creates a small staging tree and returns its root and staged paths
+func stage(t *testing.T) (string, []string) {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"volume/app/data/a.txt":  "hello",
+		"volume/app/data/b.txt":  "world",
+		"db/postgres/dump.sql":   "CREATE TABLE t();",
+		"volume/other/ignored.x": "not staged by this run",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Independent of the umask
+		if err := os.Chmod(path, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(root, "volume/app/data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "volume/app/data/link")); err != nil {
+		t.Fatal(err)
+	}
+	return root, []string{filepath.Join(root, "volume/app/data"), filepath.Join(root, "db/postgres/dump.sql")}
+}
+
+func TestBuild(t *testing.T) {
+	root, paths := stage(t)
+	m, err := Build(root, paths, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"db/postgres/dump.sql", "volume/app/data", "volume/app/data/a.txt", "volume/app/data/b.txt", "volume/app/data/link"}
+	if len(m.Entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(want), len(m.Entries), m.Entries)
+	}
+	for i, entry := range m.Entries {
+		if entry.Path != want[i] {
+			t.Errorf("entry %d: path %q, want %q", i, entry.Path, want[i])
+		}
+	}
+
+	a := m.Entries[2]
+	if a.Size != 5 || a.Mode != "-rw-r--r--" {
+		t.Errorf("unexpected entry for a.txt: %+v", a)
+	}
+	if a.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected checksum for a.txt: %s", a.SHA256)
+	}
+	if link := m.Entries[4]; link.Link != "a.txt" || link.SHA256 != "" {
+		t.Errorf("unexpected entry for symlink: %+v", link)
+	}
+	if dir := m.Entries[1]; dir.Mode != "drwxr-xr-x" || dir.Size != 0 || dir.SHA256 != "" {
+		t.Errorf("unexpected entry for directory: %+v", dir)
+	}
+	if len(m.Paths) != 2 || m.Paths[0] != "volume/app/data" || m.Paths[1] != "db/postgres/dump.sql" {
+		t.Errorf("unexpected staged paths: %v", m.Paths)
+	}
+
+	if _, err := Build(root, []string{"/etc"}, nil); err == nil {
+		t.Errorf("expected error for path outside root")
+	}
+}
+
+func TestBuild_ReusesPreviousChecksums(t *testing.T) {
+	root, paths := stage(t)
+	first, err := Build(root, paths, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Reused != 0 {
+		t.Errorf("reused %d checksums without a previous manifest", first.Reused)
+	}
+
+	// A stale checksum for an unchanged file shows that it was not read again
+	previous := *first
+	previous.Entries = slices.Clone(first.Entries)
+	for i, entry := range previous.Entries {
+		if entry.Path == "volume/app/data/a.txt" || entry.Path == "volume/app/data/b.txt" {
+			previous.Entries[i].SHA256 = "cached"
+		}
+	}
+	// b.txt changes without changing its size, but gets a new modification time
+	b := filepath.Join(root, "volume/app/data/b.txt")
+	if err := os.WriteFile(b, []byte("wound"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(b, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Build(root, paths, &previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums := make(map[string]string)
+	for _, entry := range m.Entries {
+		sums[entry.Path] = entry.SHA256
+	}
+	if sums["volume/app/data/a.txt"] != "cached" {
+		t.Errorf("checksum of unchanged file = %q, want the previous one", sums["volume/app/data/a.txt"])
+	}
+	if got := sums["volume/app/data/b.txt"]; got == "cached" || got == "" {
+		t.Errorf("checksum of modified file = %q, want a new one", got)
+	}
+	if want := first.Entries[0].SHA256; sums["db/postgres/dump.sql"] != want {
+		t.Errorf("checksum of unchanged dump = %q, want %q", sums["db/postgres/dump.sql"], want)
+	}
+	if m.Reused != 2 {
+		t.Errorf("reused %d checksums, want 2 (a.txt and dump.sql)", m.Reused)
+	}
+}
+
+func TestWriteLoad(t *testing.T) {
+	root, paths := stage(t)
+	m, err := Build(root, paths, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, FileName)
+	if err := m.Write(path); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Version != 1 || len(loaded.Entries) != len(m.Entries) || !loaded.CreatedAt.Equal(m.CreatedAt) {
+		t.Errorf("loaded manifest differs: %+v", loaded)
+	}
+	if _, err := Load(filepath.Join(root, "missing.json")); err == nil {
+		t.Errorf("expected error for missing manifest")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(root string) error
+		want   map[string]string
+	}{
+		{
+			name:   "unchanged",
+			change: func(string) error { return nil },
+			want:   map[string]string{},
+		},
+		{
+			name:   "missing file",
+			change: func(root string) error { return os.Remove(filepath.Join(root, "db/postgres/dump.sql")) },
+			want:   map[string]string{"db/postgres/dump.sql": "missing"},
+		},
+		{
+			name: "modified content",
+			change: func(root string) error {
+				return os.WriteFile(filepath.Join(root, "volume/app/data/a.txt"), []byte("jello"), 0o644)
+			},
+			want: map[string]string{"volume/app/data/a.txt": "checksum mismatch"},
+		},
+		{
+			name:   "truncated file",
+			change: func(root string) error { return os.WriteFile(filepath.Join(root, "volume/app/data/b.txt"), nil, 0o644) },
+			want:   map[string]string{"volume/app/data/b.txt": "size 0, expected 5"},
+		},
+		{
+			name:   "changed mode",
+			change: func(root string) error { return os.Chmod(filepath.Join(root, "volume/app/data/a.txt"), 0o600) },
+			want:   map[string]string{"volume/app/data/a.txt": "mode -rw-------, expected -rw-r--r--"},
+		},
+		{
+			name: "changed symlink",
+			change: func(root string) error {
+				link := filepath.Join(root, "volume/app/data/link")
+				if err := os.Remove(link); err != nil {
+					return err
+				}
+				return os.Symlink("b.txt", link)
+			},
+			want: map[string]string{"volume/app/data/link": "links to b.txt, expected a.txt"},
+		},
+		{
+			name: "extra file",
+			change: func(root string) error {
+				return os.WriteFile(filepath.Join(root, "volume/app/data/c.txt"), []byte("!"), 0o644)
+			},
+			want: map[string]string{"volume/app/data/c.txt": "not in manifest"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			root, paths := stage(t)
+			m, err := Build(root, paths, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.change(root); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := m.Verify(root)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make(map[string]string, len(problems))
+			for _, p := range problems {
+				got[p.Path] = p.Reason
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected problems %v, got %v", tc.want, got)
+			}
+			for path, reason := range tc.want {
+				if got[path] != reason {
+					t.Errorf("%s: reason %q, want %q", path, got[path], reason)
+				}
+			}
+		})
+	}
+}
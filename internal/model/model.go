@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,33 @@ const (
 
 type InstanceID string
 
+// QuiesceMode controls how containers attached to a volume are frozen while the volume is staged
+type QuiesceMode string
+
+const (
+	QuiesceNone  QuiesceMode = "none"  // leave attached containers running
+	QuiesceStop  QuiesceMode = "stop"  // stop attached containers, restart them after the backup
+	QuiescePause QuiesceMode = "pause" // pause attached containers for the duration of the staging copy
+)
+
+// StagingMode controls how a target's data is made available to the backend
+type StagingMode string
+
+const (
+	StagingCopy        StagingMode = "copy"        // copy the volume into the staging directory (default)
+	StagingSnapshot    StagingMode = "snapshot"    // mount a read-only filesystem snapshot into the staging directory
+	StagingDirect      StagingMode = "direct"      // no staging: restic reads the volume in place from a helper container
+	StagingStream      StagingMode = "stream"      // database only: pipe the dump straight into restic via --stdin
+	StagingIncremental StagingMode = "incremental" // update a persistent staging copy with rsync --delete
+)
+
+// Supported filesystem snapshot drivers for StagingSnapshot
+const (
+	SnapshotBtrfs = "btrfs"
+	SnapshotZFS   = "zfs"
+	SnapshotLVM   = "lvm" // LVM thin volumes
+)
+
 // BackupTarget represents a single volume or database to back up
 type BackupTarget struct {
 	ID         string     // stable identifier; for volume: "volume:<name>", for DB container: "container:<id>"
@@ -22,29 +50,112 @@ type BackupTarget struct {
 	PreHook    string     // command inside app/DB container (optional)
 	PostHook   string
 	// Volume specifics
-	Paths        []string // default ["/"]
-	AttachedCtrs []string // containers using the volume (for hooks)
-	StopAttached bool     // whether to stop attached containers during backup
+	Paths        []string    // default ["/"]
+	AttachedCtrs []string    // containers using the volume (for hooks)
+	Quiesce      QuiesceMode // how attached containers are quiesced during staging
+	Staging      StagingMode // how the target is staged (copy, snapshot, direct or incremental for volumes; copy or stream for databases)
+	Snapshot     string      // snapshot driver for StagingSnapshot: btrfs, zfs or lvm
 	// DB specifics
 	DBKind      string // "postgres", "mysql", ...
 	ContainerID string // DB container to exec dump in
 	DumpArgs    []string
 }
 
-// InstanceBackupSchedule represents all targets that should be backed up together for an instance
+// OverlapPolicy controls what happens when a run of an instance is due while the previous one is still active
+type OverlapPolicy string
+
+const (
+	OverlapSkip           OverlapPolicy = "skip"            // record the new run as skipped (default)
+	OverlapQueue          OverlapPolicy = "queue"           // start the new run after the previous one has finished
+	OverlapCancelPrevious OverlapPolicy = "cancel-previous" // cancel the previous run and start the new one after its cleanup
+)
+
+// RetryPolicy controls how often failed target staging and backend uploads are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // attempts per step, including the first (1 = no retries)
+	InitialBackoff time.Duration // wait before the first retry
+	Multiplier     float64       // factor applied to the wait after every retry
+}
+
+// Timeouts limit how long a run and its steps may take (0 = no limit)
+type Timeouts struct {
+	Job     time.Duration // whole run, from start to the last upload
+	Hook    time.Duration // each pre- and post-hook
+	Dump    time.Duration // each database dump, including copying it out of the container
+	Staging time.Duration // copying or snapshotting each volume into staging, including quiescing
+}
+
+// BlackoutPolicy controls what happens to a scheduled run that falls into a blackout window
+type BlackoutPolicy string
+
+const (
+	BlackoutDefer BlackoutPolicy = "defer" // start the run when the window ends (default)
+	BlackoutSkip  BlackoutPolicy = "skip"  // record the run as skipped
+)
+
+// BlackoutWindow is a recurring period during which scheduled runs do not start.
+// It is either a cron window (Cron and Duration) or a time range on weekdays (Days, Start and End).
+type BlackoutWindow struct {
+	Cron     string         // cron windows: the window opens at every tick of this schedule
+	Duration time.Duration  // cron windows: how long the window stays open
+	Days     []time.Weekday // time ranges: days the window opens on (empty = every day)
+	Start    time.Duration  // time ranges: opening time as offset from midnight
+	End      time.Duration  // time ranges: closing time as offset from midnight (<= Start: the next day)
+	Timezone string         // IANA time zone the window is evaluated in ("" = local time)
+	Policy   BlackoutPolicy
+}
+
+// DefaultHelperImage is the image used for helper containers unless configured otherwise
+const DefaultHelperImage = "alpine:3.20"
+
+// HelperSettings configures the helper containers that copy and measure volume data
+type HelperSettings struct {
+	Image  string  // helper image
+	CPUs   float64 // CPU limit (0 = unlimited)
+	Memory int64   // memory limit in bytes (0 = unlimited)
+}
+
+// InstanceBackupSchedule is a group of an instance's targets that share a cron schedule.
+// An instance has one schedule per distinct target schedule.
 type InstanceBackupSchedule struct {
 	InstanceID   InstanceID
-	ScheduleCron string // cron schedule from config
+	ScheduleCron string        // cron schedule from config (target schedule or instance schedule)
+	Timezone     string        // IANA time zone the schedule is evaluated in ("" = local time)
+	Jitter       time.Duration // maximum random delay of scheduled runs (0 = none)
 	Targets      []BackupTarget
-	Retention    Retention // Common retention policy (from first target or config default)
+	Retention    Retention        // Common retention policy (from first target or config default)
+	Helper       HelperSettings   // helper container settings (instance > global > defaults)
+	Overlap      OverlapPolicy    // what to do when a run is due while the previous one is still active
+	Retry        RetryPolicy      // retries of failed target staging and backend uploads
+	Timeouts     Timeouts         // limits of the whole run and its hooks, dumps and staging
+	CatchUp      bool             // start a run at startup if a scheduled time was missed while Marina was down
+	Blackouts    []BlackoutWindow // windows in which scheduled runs are deferred or skipped (global and instance)
+	After        []InstanceID     // chained schedule: runs when all these instances have completed (no cron schedule)
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
 
+// Key identifies the schedule group (instance ID and cron schedule)
+func (s InstanceBackupSchedule) Key() string {
+	return string(s.InstanceID) + "@" + s.ScheduleCron
+}
+
+// Describe returns the cron schedule, or the upstream instances of a chained schedule
+func (s InstanceBackupSchedule) Describe() string {
+	if len(s.After) == 0 {
+		return s.ScheduleCron
+	}
+	upstream := make([]string, len(s.After))
+	for i, id := range s.After {
+		upstream[i] = string(id)
+	}
+	return "after " + strings.Join(upstream, ", ")
+}
+
 type InstanceBackupScheduleView struct {
 	InstanceID           InstanceID      `json:"instanceId"`
 	NodeName             string          `json:"nodeName,omitempty"` // Name of the node (for mesh mode)
-	ScheduleCron         string          `json:"scheduleCron"`       // cron schedule from config
+	ScheduleCron         string          `json:"scheduleCron"`       // cron schedule from config ("; "-separated if targets have their own schedules, "after ..." for chained instances)
 	NextRunAt            *time.Time      `json:"nextRunAt"`          // next scheduled run (nil if not scheduled)
 	TargetIDs            []string        `json:"targetIds"`
 	Retention            Retention       `json:"retention"` // Common retention policy (from first target or config default)
@@ -54,21 +165,78 @@ type InstanceBackupScheduleView struct {
 	LatestJobCompletedAt *time.Time      `json:"latestJobCompletedAt,omitempty"` // completion time of most recent job
 }
 
-type Retention struct {
-	KeepDaily   int `json:"keepDaily"`
-	KeepWeekly  int `json:"keepWeekly"`
-	KeepMonthly int `json:"keepMonthly"`
+// StagingSize records the estimated and the actual staged size of a target in its latest run.
+// The staged size also serves as the estimate for targets that cannot be measured up front.
+type StagingSize struct {
+	InstanceID     InstanceID `json:"instanceId"`
+	TargetID       string     `json:"targetId"`
+	JobStatusID    int        `json:"jobStatusId"`    // job that staged the target
+	EstimatedBytes int64      `json:"estimatedBytes"` // preflight estimate (-1 if none was available)
+	StagedBytes    int64      `json:"stagedBytes"`    // bytes actually written to staging
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// JobTarget is the result of one target of a job
+type JobTarget struct {
+	JobStatusID       int            `json:"jobStatusId"`
+	TargetID          string         `json:"targetId"`
+	Type              TargetType     `json:"type"`
+	Status            JobStatusState `json:"status"`            // in_progress once staged, then success or failed (aborted if the run ended before the upload)
+	Message           string         `json:"message,omitempty"` // error of a failed target
+	StagedBytes       int64          `json:"stagedBytes"`       // bytes written to staging (0 for direct and stream staging)
+	StagingDurationMs int64          `json:"stagingDurationMs"` // time spent staging, dumping or streaming the target, including retries
+	DBKind            string         `json:"dbKind,omitempty"`  // kind of the database dump (configured or detected)
+	UpdatedAt         time.Time      `json:"updatedAt"`
 }
 
-type JobState string
+// JobPhaseName names a timed phase of a run
+type JobPhaseName string
 
 const (
-	JobQueued  JobState = "queued"
-	JobRunning JobState = "running"
-	JobSuccess JobState = "success"
-	JobFailed  JobState = "failed"
+	PhasePreflight JobPhaseName = "preflight" // staging size estimate and free space check
+	PhasePreHook   JobPhaseName = "pre-hook"
+	PhaseQuiesce   JobPhaseName = "quiesce" // stopping or pausing attached containers
+	PhaseStaging   JobPhaseName = "staging" // copying, syncing or snapshotting a volume
+	PhaseDump      JobPhaseName = "dump"    // creating a database dump and copying it out of the container
+	PhaseStream    JobPhaseName = "stream"  // piping a database dump into restic
+	PhasePostHook  JobPhaseName = "post-hook"
+	PhaseUpload    JobPhaseName = "upload"    // backing up the staged paths (one phase per attempt)
+	PhaseRetention JobPhaseName = "retention" // applying the retention policy (forget --prune)
+	PhaseCleanup   JobPhaseName = "cleanup"   // restarting containers and removing the staging directory
 )
 
+// JobPhase is the start and end of one phase of a job, for the whole job or one of its targets
+type JobPhase struct {
+	JobStatusID int          `json:"jobStatusId"`
+	TargetID    string       `json:"targetId,omitempty"` // empty for phases of the whole job
+	Phase       JobPhaseName `json:"phase"`
+	StartedAt   time.Time    `json:"startedAt"`
+	EndedAt     time.Time    `json:"endedAt"`
+	DurationMs  int64        `json:"durationMs"`
+	Error       string       `json:"error,omitempty"` // set if the phase failed
+}
+
+// JobPhaseTotals sums the phase durations of one job, for comparing runs of an instance over time
+type JobPhaseTotals struct {
+	JobStatusID   int                    `json:"jobStatusId"`
+	Status        JobStatusState         `json:"status"`
+	LastStartedAt *time.Time             `json:"lastStartedAt"`
+	DurationsMs   map[JobPhaseName]int64 `json:"durationsMs"` // total time per phase across all targets
+}
+
+// JobDetails is a job with the results of its targets and the timeline of its phases
+type JobDetails struct {
+	*JobStatus
+	Targets []*JobTarget `json:"targets"`
+	Phases  []*JobPhase  `json:"phases"`
+}
+
+type Retention struct {
+	KeepDaily   int `json:"keepDaily"`
+	KeepWeekly  int `json:"keepWeekly"`
+	KeepMonthly int `json:"keepMonthly"`
+}
+
 // JobStatusState represents the current status of a backup job
 type JobStatusState string
 
@@ -78,7 +246,10 @@ const (
 	StatusPartialSuccess JobStatusState = "partial_success" // completed with warnings
 	StatusFailed         JobStatusState = "failed"          // hard error
 	StatusScheduled      JobStatusState = "scheduled"       // scheduled but not yet executed
+	StatusQueued         JobStatusState = "queued"          // waiting for a free slot (concurrency limits)
 	StatusAborted        JobStatusState = "aborted"         // interrupted by restart/shutdown
+	StatusSkipped        JobStatusState = "skipped"         // not run because of the overlap policy or a blackout window (see Message)
+	StatusCancelled      JobStatusState = "cancelled"       // cancelled on request (API)
 )
 
 // JobStatus represents the persistent status of a backup target
@@ -95,6 +266,66 @@ type JobStatus struct {
 	LastCompletedAt       *time.Time     `json:"lastCompletedAt"`       // when last backup completed (nil if never completed)
 	LastTargetsSuccessful int            `json:"lastTargetsSuccessful"` // number of successfully backed up targets in last run
 	LastTargetsTotal      int            `json:"lastTargetsTotal"`      // total number of targets in last run
+	Message               string         `json:"message,omitempty"`     // reason for skipped or aborted runs
+	Attempts              int            `json:"attempts"`              // highest attempt number any staging or upload step needed (0 if not run)
+	TargetIDs             []string       `json:"targetIds,omitempty"`   // targets covered by this run
 	CreatedAt             time.Time      `json:"createdAt"`             // when this job was first discovered
 	UpdatedAt             time.Time      `json:"updatedAt"`             // last status update
 }
+
+// ControlCommandType identifies a request from the API server to the manager
+type ControlCommandType string
+
+const (
+	CommandCancel ControlCommandType = "cancel"  // cancel a running or queued job
+	CommandRun    ControlCommandType = "run"     // start a manual run of an instance (optionally a subset of its targets)
+	CommandDryRun ControlCommandType = "dry_run" // report what a run of an instance would do
+)
+
+// ControlCommand is a request from the API server to the manager. The processes only share the
+// database, so commands are stored there and picked up by the manager.
+type ControlCommand struct {
+	ID          int                `json:"id"`
+	Type        ControlCommandType `json:"type"`
+	JobStatusID int                `json:"jobStatusId,omitempty"` // job the command applies to
+	InstanceID  InstanceID         `json:"instanceId,omitempty"`  // instance the command applies to
+	TargetIDs   []string           `json:"targetIds,omitempty"`   // targets of a run command (all if empty)
+	Result      string             `json:"result,omitempty"`      // outcome reported by the manager
+	Report      *DryRunReport      `json:"report,omitempty"`      // report of a dry-run command
+	CreatedAt   time.Time          `json:"createdAt"`
+	ProcessedAt *time.Time         `json:"processedAt"` // nil while pending
+}
+
+// DryRunReport describes what a run of an instance would do. It is built without stopping containers,
+// running hooks or dumps, staging data or writing to the repository.
+type DryRunReport struct {
+	InstanceID            InstanceID     `json:"instanceId"`
+	Backend               string         `json:"backend"`                    // restic or custom
+	Image                 string         `json:"image,omitempty"`            // custom backup image
+	Targets               []DryRunTarget `json:"targets"`                    // all targets of all schedules of the instance
+	EstimatedStagingBytes int64          `json:"estimatedStagingBytes"`      // sum of the known staging estimates
+	FreeStagingBytes      int64          `json:"freeStagingBytes"`           // free space on the staging mount (-1 if unknown)
+	Commands              []string       `json:"commands,omitempty"`         // restic commands of the run, in order
+	Retention             Retention      `json:"retention"`                  // retention applied after the run
+	RetentionPreview      string         `json:"retentionPreview,omitempty"` // output of 'restic forget --dry-run'
+	Problems              []string       `json:"problems,omitempty"`         // reasons the run would fail or be incomplete
+	CreatedAt             time.Time      `json:"createdAt"`
+}
+
+// DryRunTarget describes how a run would back up one target
+type DryRunTarget struct {
+	ID                 string      `json:"id"`
+	Type               TargetType  `json:"type"`
+	Name               string      `json:"name"`
+	Schedule           string      `json:"schedule"` // schedule of the run the target belongs to
+	Staging            StagingMode `json:"staging,omitempty"`
+	Quiesce            QuiesceMode `json:"quiesce,omitempty"`
+	QuiescedContainers []string    `json:"quiescedContainers,omitempty"` // containers that would be stopped or paused
+	HookContainer      string      `json:"hookContainer,omitempty"`      // container the pre- and post-hook would run in
+	ContainerID        string      `json:"containerId,omitempty"`        // resolved database container
+	DBKind             string      `json:"dbKind,omitempty"`             // configured or detected database kind
+	DumpCommand        string      `json:"dumpCommand,omitempty"`        // command that would create the dump
+	EstimatedBytes     int64       `json:"estimatedBytes"`               // staging space needed (-1 if unknown)
+	Paths              []string    `json:"paths,omitempty"`              // paths passed to the backend
+	Error              string      `json:"error,omitempty"`              // why backing up the target would fail
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
@@ -669,6 +671,66 @@ func (c *Client) fetchSystemLogsFromPeer(ctx context.Context, peerURL string, le
 	return result
 }
 
+// PeerResponse is the raw response of a request forwarded to a peer node
+type PeerResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Forward sends a request to one of the configured peer nodes and returns its response as is.
+// It is used for actions (like cancelling a job) that have to be executed by the node owning the data.
+func (c *Client) Forward(ctx context.Context, method, peerURL, path string, body []byte) (*PeerResponse, error) {
+	if !slices.Contains(c.peers, peerURL) {
+		return nil, fmt.Errorf("unknown peer %s", peerURL)
+	}
+
+	url := peerURL + path
+	send := func() (*PeerResponse, error) {
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(reqCtx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		// Mark this as a mesh request so the peer handles it locally
+		req.Header.Set("X-Marina-Mesh", "true")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		c.addAuthHeader(req)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("forward request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		return &PeerResponse{StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: data}, nil
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+
+	// If we get 401, the token might be expired - clear it and retry once
+	if resp.StatusCode == http.StatusUnauthorized && c.password != "" {
+		c.tokensMu.Lock()
+		delete(c.tokens, peerURL)
+		c.tokensMu.Unlock()
+
+		return send()
+	}
+
+	return resp, nil
+}
+
 // addAuthHeader adds authentication header to the request
 func (c *Client) addAuthHeader(req *http.Request) {
 	if c.password == "" {
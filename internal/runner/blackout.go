@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/helpers"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// startScheduled queues a run that was not requested manually (cron tick, catch-up). If a blackout
+// window of the schedule is active, the run is deferred to the end of the window or recorded as
+// skipped, depending on the window's policy. A schedule group has at most one deferred run.
+func (r *Runner) startScheduled(schedule model.InstanceBackupSchedule, trigger string) {
+	r.mu.Lock()
+	stopping := r.stopping
+	r.mu.Unlock()
+	if stopping {
+		r.Logger.Info("%s of instance %s dropped, marina is shutting down", trigger, schedule.InstanceID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	end, policy, active := helpers.BlackoutAt(schedule.Blackouts, r.now())
+	if !active {
+		if _, err := r.enqueue(ctx, schedule, trigger); err != nil {
+			r.Logger.Error("failed to queue backup of instance %s: %v", schedule.InstanceID, err)
+		}
+		return
+	}
+
+	if policy == model.BlackoutSkip {
+		r.skipRun(ctx, schedule, trigger, fmt.Sprintf("blackout window until %s", end.Format(time.RFC3339)))
+		return
+	}
+
+	key := schedule.Key()
+	r.mu.Lock()
+	_, pending := r.deferred[key]
+	if !pending {
+		r.deferred[key] = end
+	}
+	r.mu.Unlock()
+	if pending {
+		r.Logger.Info("%s of instance %s dropped, a run is already deferred by a blackout window", trigger, schedule.InstanceID)
+		return
+	}
+
+	r.Logger.Info("%s of instance %s deferred until %s (blackout window)", trigger, schedule.InstanceID, end.Format(time.RFC3339))
+	r.updateNextRunTime(schedule.InstanceID)
+	time.AfterFunc(end.Sub(r.now()), func() {
+		r.mu.Lock()
+		delete(r.deferred, key)
+		current, ok := r.jobs[key] // the config may have been reloaded in the meantime
+		r.mu.Unlock()
+		if !ok {
+			r.Logger.Info("deferred run of instance %s dropped, its schedule no longer exists", schedule.InstanceID)
+			return
+		}
+		r.startScheduled(current, trigger+" (deferred by blackout window)")
+	})
+}
+
+// skipRun records a run that is not started as skipped
+func (r *Runner) skipRun(ctx context.Context, schedule model.InstanceBackupSchedule, trigger, reason string) {
+	if r.DB == nil {
+		r.Logger.Warn("%s of instance %s skipped: %s", trigger, schedule.InstanceID, reason)
+		return
+	}
+	jobStatus, err := r.DB.ScheduleNewJob(ctx, string(schedule.InstanceID), targetIDs(schedule.Targets))
+	if err != nil {
+		r.Logger.Error("failed to create job status: %v", err)
+		return
+	}
+	r.markSkipped(ctx, jobStatus.ID, reason)
+	r.Logger.NewJobLogger(string(schedule.InstanceID), jobStatus.ID, jobStatus.IID).Warn("%s skipped: %s", trigger, reason)
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/helpers"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// catchUpDelay gives containers started at boot time to come up before a catch-up run starts
+var catchUpDelay = time.Minute
+
+// CatchUpMissedRuns starts a run of every schedule group with catch-up enabled whose last scheduled
+// time passed while Marina was not running. A group is behind if its schedule had a tick between the
+// start of its last completed run and now; groups that never completed a run are left alone.
+// Must be called after SyncBackups and ResumeQueuedJobs.
+func (r *Runner) CatchUpMissedRuns(ctx context.Context) {
+	if r.DB == nil {
+		return
+	}
+	now := r.now()
+	completed := make(map[model.InstanceID][]*model.JobStatus)
+
+	// Copy the schedule groups under r.mu (cron callbacks are already live); the queries run without it
+	r.mu.Lock()
+	var groups []model.InstanceBackupSchedule
+	for _, key := range slices.Sorted(maps.Keys(r.jobs)) {
+		groups = append(groups, r.jobs[key])
+	}
+	r.mu.Unlock()
+
+	for _, job := range groups {
+		if !job.CatchUp || len(job.After) > 0 {
+			continue
+		}
+
+		if _, ok := completed[job.InstanceID]; !ok {
+			jobs, err := r.DB.GetCompletedJobs(ctx, string(job.InstanceID))
+			if err != nil {
+				r.Logger.Warn("catch-up check of instance %s failed: %v", job.InstanceID, err)
+				continue
+			}
+			completed[job.InstanceID] = jobs
+		}
+		last := lastRunOfGroup(completed[job.InstanceID], job)
+		if last == nil {
+			continue
+		}
+
+		schedule, err := helpers.CronParser.Parse(helpers.CronSpec(job.ScheduleCron, job.Timezone))
+		if err != nil {
+			r.Logger.Warn("catch-up check of instance %s failed: %v", job.InstanceID, err)
+			continue
+		}
+		missed := schedule.Next(*last)
+		if !missed.Before(now) {
+			continue
+		}
+
+		r.Logger.Info("instance %s missed its run at %s (schedule %s), starting a catch-up run in %v",
+			job.InstanceID, missed.Format(time.RFC3339), job.ScheduleCron, catchUpDelay)
+		trigger := "catch-up run for missed " + missed.Format(time.RFC3339)
+		time.AfterFunc(catchUpDelay, func() { r.startCatchUp(job, trigger) })
+	}
+}
+
+// startCatchUp queues the catch-up run of a schedule group unless a run of it is already active
+func (r *Runner) startCatchUp(job model.InstanceBackupSchedule, trigger string) {
+	r.mu.Lock()
+	active := r.activeRunLocked(job.Key())
+	r.mu.Unlock()
+	if active != "" {
+		r.Logger.Info("catch-up run of instance %s not needed, run %s is already active", job.InstanceID, active)
+		return
+	}
+	r.startScheduled(job, trigger)
+}
+
+// lastRunOfGroup returns the start time of the newest completed job (newest first) that covered
+// all targets of the schedule group. Jobs without recorded target IDs covered the whole instance.
+func lastRunOfGroup(jobs []*model.JobStatus, group model.InstanceBackupSchedule) *time.Time {
+	for _, job := range jobs {
+		covered := len(job.TargetIDs) == 0 || !slices.ContainsFunc(group.Targets, func(target model.BackupTarget) bool {
+			return !slices.Contains(job.TargetIDs, target.ID)
+		})
+		if covered {
+			return job.LastStartedAt
+		}
+	}
+	return nil
+}
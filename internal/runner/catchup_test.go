@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/database"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// catchUpNow is the fixed clock of the catch-up tests; the daily schedule last ticked at 03:00 the same day
+var catchUpNow = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+// catchUpSchedule returns a daily 03:00 UTC schedule group with catch-up enabled
+func catchUpSchedule() model.InstanceBackupSchedule {
+	schedule := testSchedule("app")
+	schedule.Timezone = "UTC"
+	schedule.CatchUp = true
+	return schedule
+}
+
+// newCatchUpRunner creates a runner with the fixed clock and without the catch-up delay
+func newCatchUpRunner(t *testing.T, db *database.DB, schedule model.InstanceBackupSchedule) (*Runner, *fakeRuns) {
+	t.Helper()
+	delay := catchUpDelay
+	catchUpDelay = 0
+	t.Cleanup(func() { catchUpDelay = delay })
+
+	r, runs := newTestRunner(t, db, map[model.InstanceID]backend.Backend{"app": newFakeBackend(backend.BackendTypeRestic)}, schedule)
+	r.now = func() time.Time { return catchUpNow }
+	return r, runs
+}
+
+// recordCompletedRun stores a finished run of the instance that started at the given time
+func recordCompletedRun(t *testing.T, db *database.DB, instanceID model.InstanceID, targetIDs []string, status model.JobStatusState, startedAt time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	job, err := db.ScheduleNewJob(ctx, string(instanceID), targetIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	completedAt := startedAt.Add(10 * time.Minute)
+	job.Status = status
+	job.LastStartedAt = &startedAt
+	job.LastCompletedAt = &completedAt
+	if err := db.UpdateJobStatus(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCatchUpMissedRuns(t *testing.T) {
+	tests := []struct {
+		name      string
+		noCatchUp bool
+		lastRun   *time.Time // start of the last completed run (nil: none)
+		status    model.JobStatusState
+		targetIDs []string
+		wantRun   bool
+	}{
+		{name: "never ran", wantRun: false},
+		{name: "ran after the last tick", lastRun: ptr(catchUpNow.Add(-8*time.Hour + time.Minute)), wantRun: false},
+		{name: "missed the last tick", lastRun: ptr(catchUpNow.Add(-33 * time.Hour)), wantRun: true},
+		{name: "last run failed", lastRun: ptr(catchUpNow.Add(-33 * time.Hour)), status: model.StatusFailed, wantRun: true},
+		{name: "catch-up disabled", noCatchUp: true, lastRun: ptr(catchUpNow.Add(-33 * time.Hour)), wantRun: false},
+		{name: "newer run covered other targets only", lastRun: ptr(catchUpNow.Add(-33 * time.Hour)), targetIDs: []string{"volume:other"}, wantRun: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			schedule := catchUpSchedule()
+			schedule.CatchUp = !tt.noCatchUp
+			r, runs := newCatchUpRunner(t, db, schedule)
+			if tt.lastRun != nil {
+				status := tt.status
+				if status == "" {
+					status = model.StatusSuccess
+				}
+				targets := tt.targetIDs
+				if targets == nil {
+					targets = []string{"volume:app"}
+				}
+				recordCompletedRun(t, db, "app", targets, status, *tt.lastRun)
+			}
+
+			r.CatchUpMissedRuns(context.Background())
+			if tt.wantRun {
+				runs.expectStarted(t, "app")
+			}
+			runs.expectNoStart(t)
+		})
+	}
+}
+
+func TestCatchUpMissedRuns_OnlyOnce(t *testing.T) {
+	db := openTestDB(t)
+	r, runs := newCatchUpRunner(t, db, catchUpSchedule())
+	// Five missed ticks still make up a single run
+	recordCompletedRun(t, db, "app", []string{"volume:app"}, model.StatusSuccess, catchUpNow.Add(-5*24*time.Hour))
+
+	r.CatchUpMissedRuns(context.Background())
+	runs.expectStarted(t, "app")
+	runs.expectNoStart(t)
+
+	// A second check while the catch-up run is active does not start another one
+	r.CatchUpMissedRuns(context.Background())
+	runs.expectNoStart(t)
+}
+
+func TestCatchUpMissedRuns_Blackouts(t *testing.T) {
+	// A window from 11:00 to 13:00 UTC covers the fixed clock
+	window := model.BlackoutWindow{Start: 11 * time.Hour, End: 13 * time.Hour, Timezone: "UTC"}
+	windowEnd := time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)
+
+	t.Run("skip", func(t *testing.T) {
+		db := openTestDB(t)
+		schedule := catchUpSchedule()
+		window := window
+		window.Policy = model.BlackoutSkip
+		schedule.Blackouts = []model.BlackoutWindow{window}
+		r, runs := newCatchUpRunner(t, db, schedule)
+		recordCompletedRun(t, db, "app", []string{"volume:app"}, model.StatusSuccess, catchUpNow.Add(-33*time.Hour))
+
+		r.CatchUpMissedRuns(context.Background())
+		runs.expectNoStart(t)
+
+		jobs, err := db.GetJobStatus(context.Background(), "app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var skipped *model.JobStatus
+		for _, job := range jobs {
+			if job.Status == model.StatusSkipped {
+				skipped = job
+			}
+		}
+		if skipped == nil {
+			t.Fatal("catch-up run in a skip window not recorded as skipped")
+		}
+		if !strings.Contains(skipped.Message, "blackout window until "+windowEnd.Format(time.RFC3339)) {
+			t.Errorf("message = %q", skipped.Message)
+		}
+	})
+
+	t.Run("defer", func(t *testing.T) {
+		db := openTestDB(t)
+		schedule := catchUpSchedule()
+		window := window
+		window.Policy = model.BlackoutDefer
+		schedule.Blackouts = []model.BlackoutWindow{window}
+		r, runs := newCatchUpRunner(t, db, schedule)
+		recordCompletedRun(t, db, "app", []string{"volume:app"}, model.StatusSuccess, catchUpNow.Add(-33*time.Hour))
+
+		r.CatchUpMissedRuns(context.Background())
+		runs.expectNoStart(t)
+
+		r.mu.Lock()
+		deferredUntil, ok := r.deferred[schedule.Key()]
+		r.mu.Unlock()
+		if !ok || !deferredUntil.Equal(windowEnd) {
+			t.Errorf("catch-up run deferred until %v (deferred: %v), want %v", deferredUntil, ok, windowEnd)
+		}
+	})
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// completeUpstream records a successful (or partially successful) run of an instance and starts the
+// chained schedules whose upstream instances have all completed since their last chained run
+func (r *Runner) completeUpstream(instanceID model.InstanceID, jobStatusIID int) {
+	var ready []model.InstanceBackupSchedule
+
+	r.mu.Lock()
+	for _, key := range slices.Sorted(maps.Keys(r.jobs)) {
+		job := r.jobs[key]
+		if !slices.Contains(job.After, instanceID) {
+			continue
+		}
+		completed := r.chainProgress[key]
+		if completed == nil {
+			completed = make(map[model.InstanceID]bool)
+			r.chainProgress[key] = completed
+		}
+		completed[instanceID] = true
+		if !slices.ContainsFunc(job.After, func(upstream model.InstanceID) bool { return !completed[upstream] }) {
+			delete(r.chainProgress, key)
+			ready = append(ready, job)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, job := range ready {
+		r.startScheduled(job, fmt.Sprintf("chained run after %s #%d", instanceID, jobStatusIID))
+	}
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// newChainRunner creates a runner with the upstream instances and a "report" instance chained after them
+func newChainRunner(t *testing.T, upstream ...model.InstanceID) (*Runner, *fakeRuns) {
+	t.Helper()
+	instances := map[model.InstanceID]backend.Backend{"report": newFakeBackend(backend.BackendTypeRestic)}
+	var schedules []model.InstanceBackupSchedule
+	for _, id := range upstream {
+		instances[id] = newFakeBackend(backend.BackendTypeRestic)
+		schedules = append(schedules, testSchedule(id))
+	}
+	report := testSchedule("report")
+	report.ScheduleCron = ""
+	report.After = upstream
+	schedules = append(schedules, report)
+	return newTestRunner(t, openTestDB(t), instances, schedules...)
+}
+
+func TestChain_RunsAfterUpstreamSuccess(t *testing.T) {
+	r, runs := newChainRunner(t, "app")
+
+	trigger(t, r, "app")
+	runs.expectStarted(t, "app")["app"].finish <- nil
+
+	run := runs.expectStarted(t, "report")["report"]
+	status := waitForStatus(t, r.DB, run.jobStatusID, model.StatusInProgress)
+	if status.InstanceID != "report" {
+		t.Errorf("chained job belongs to %s", status.InstanceID)
+	}
+}
+
+func TestChain_WaitsForAllUpstreams(t *testing.T) {
+	r, runs := newChainRunner(t, "app", "db")
+
+	// Completing the same upstream twice does not count for the other one
+	for range 2 {
+		trigger(t, r, "app")
+		runs.expectStarted(t, "app")["app"].finish <- nil
+		runs.expectNoStart(t)
+	}
+
+	trigger(t, r, "db")
+	runs.expectStarted(t, "db")["db"].finish <- nil
+	runs.expectStarted(t, "report")["report"].finish <- nil
+
+	// The chained run resets the progress: both upstreams have to complete again
+	trigger(t, r, "db")
+	runs.expectStarted(t, "db")["db"].finish <- nil
+	runs.expectNoStart(t)
+	r.mu.Lock()
+	progress := r.chainProgress["report@"]
+	r.mu.Unlock()
+	if !progress["db"] || progress["app"] {
+		t.Errorf("chain progress = %v, want only db completed", progress)
+	}
+
+	trigger(t, r, "app")
+	runs.expectStarted(t, "app")["app"].finish <- nil
+	runs.expectStarted(t, "report")
+}
+
+func TestChain_NoRunAfterUpstreamFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(r *Runner, run *fakeRun) error
+	}{
+		{
+			name: "failed",
+			stop: func(_ *Runner, run *fakeRun) error {
+				run.finish <- errUpload
+				return nil
+			},
+		},
+		{
+			name: "cancelled",
+			stop: func(r *Runner, run *fakeRun) error {
+				return r.CancelJob(context.Background(), run.jobStatusID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, runs := newChainRunner(t, "app")
+
+			trigger(t, r, "app")
+			run := runs.expectStarted(t, "app")["app"]
+			if err := tt.stop(r, run); err != nil {
+				t.Fatal(err)
+			}
+			runs.expectNoStart(t)
+
+			r.mu.Lock()
+			progress := r.chainProgress["report@"]
+			r.mu.Unlock()
+			if len(progress) != 0 {
+				t.Errorf("chain progress = %v, want none", progress)
+			}
+		})
+	}
+}
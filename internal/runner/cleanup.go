@@ -0,0 +1,247 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/polarfoxDev/marina/internal/docker"
+	"github.com/polarfoxDev/marina/internal/helpers"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// stagingRunDirPattern matches the per-run staging directories (/backup/<instance>/<timestamp>)
+var stagingRunDirPattern = regexp.MustCompile(`^\d{8}-\d{6}$`)
+
+// cleanupDumpDirsScript removes leftover dump directories (/tmp/marina-<timestamp>) inside a
+// database container and prints the size of each removed directory in kilobytes
+const cleanupDumpDirsScript = `for d in /tmp/marina-[0-9]*-[0-9]*; do
+  [ -d "$d" ] || continue
+  du -sk "$d" | cut -f1
+  rm -rf "$d"
+done`
+
+// CleanupOrphanedStaging removes staging data left behind by runs that never reached their
+// deferred cleanup (crash, kill, power loss): per-run staging directories below /backup and
+// dump directories in the /tmp of configured database containers. Incremental staging copies
+// found in an interrupted run are moved back to their persistent location; copies of volumes
+// that are no longer configured for incremental staging are removed.
+// Must be called at startup, before any backup is running. Returns the number of bytes reclaimed.
+func (r *Runner) CleanupOrphanedStaging(ctx context.Context, schedules []model.InstanceBackupSchedule) int64 {
+	// Volumes with incremental staging per instance
+	incremental := make(map[string]map[string]bool)
+	for _, schedule := range schedules {
+		for _, target := range schedule.Targets {
+			if target.Type == model.TargetVolume && target.Staging == model.StagingIncremental {
+				if incremental[string(schedule.InstanceID)] == nil {
+					incremental[string(schedule.InstanceID)] = make(map[string]bool)
+				}
+				incremental[string(schedule.InstanceID)][target.Name] = true
+			}
+		}
+	}
+
+	reclaimed := r.cleanupStagingDirs(incremental)
+	reclaimed += r.cleanupContainerDumps(ctx, schedules)
+	if reclaimed > 0 {
+		r.Logger.Info("reclaimed %s of orphaned staging data", helpers.FormatBytes(reclaimed))
+	}
+	return reclaimed
+}
+
+// cleanupStagingDirs removes all /backup/<instance>/<timestamp> directories and incremental
+// copies of volumes that are not in the given set (instance ID -> volume names)
+func (r *Runner) cleanupStagingDirs(incremental map[string]map[string]bool) int64 {
+	instanceDirs, err := os.ReadDir(stagingRoot)
+	if err != nil {
+		r.Logger.Warn("failed to read staging directory: %v", err)
+		return 0
+	}
+
+	mounts, err := mountPoints()
+	if err != nil {
+		r.Logger.Warn("failed to read mount table: %v", err)
+		return 0
+	}
+
+	var reclaimed int64
+	for _, instanceDir := range instanceDirs {
+		if !instanceDir.IsDir() {
+			continue
+		}
+		runDirs, err := os.ReadDir(filepath.Join(stagingRoot, instanceDir.Name()))
+		if err != nil {
+			r.Logger.Warn("failed to read staging directory of %s: %v", instanceDir.Name(), err)
+			continue
+		}
+		for _, runDir := range runDirs {
+			if !runDir.IsDir() || !stagingRunDirPattern.MatchString(runDir.Name()) {
+				continue
+			}
+			dir := filepath.Join(stagingRoot, instanceDir.Name(), runDir.Name())
+
+			// Never delete through a leftover snapshot mount; it has to be released on the host
+			if mounted := mountsBelow(mounts, dir); len(mounted) > 0 {
+				r.Logger.Warn("not removing orphaned staging directory %s: still mounted at %s (unmount it and destroy the snapshot on the host)", dir, strings.Join(mounted, ", "))
+				continue
+			}
+
+			r.rescueIncrementalCopies(instanceDir.Name(), dir, incremental[instanceDir.Name()])
+
+			size, err := stagedSize([]string{dir})
+			if err != nil {
+				r.Logger.Debug("failed to measure %s: %v", dir, err)
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				r.Logger.Warn("failed to remove orphaned staging directory %s: %v", dir, err)
+				continue
+			}
+			r.Logger.Info("removed orphaned staging directory %s (%s)", dir, helpers.FormatBytes(size))
+			reclaimed += size
+		}
+
+		// Remove incremental copies of volumes that no longer use incremental staging
+		cacheRoot := filepath.Join(stagingRoot, instanceDir.Name(), "incremental", "volume")
+		cached, err := os.ReadDir(cacheRoot)
+		if err != nil {
+			continue
+		}
+		for _, entry := range cached {
+			if !entry.IsDir() || incremental[instanceDir.Name()][entry.Name()] {
+				continue
+			}
+			dir := filepath.Join(cacheRoot, entry.Name())
+			size, _ := stagedSize([]string{dir})
+			if err := os.RemoveAll(dir); err != nil {
+				r.Logger.Warn("failed to remove unused incremental copy %s: %v", dir, err)
+				continue
+			}
+			r.Logger.Info("removed unused incremental copy %s (%s)", dir, helpers.FormatBytes(size))
+			reclaimed += size
+		}
+	}
+	return reclaimed
+}
+
+// rescueIncrementalCopies moves incremental staging copies that an interrupted run left in its
+// staging directory back to their persistent location, so the next run stays incremental
+func (r *Runner) rescueIncrementalCopies(instanceID, runDir string, volumes map[string]bool) {
+	for volumeName := range volumes {
+		staged := filepath.Join(runDir, "volume", volumeName)
+		cacheDir := docker.IncrementalCacheDir(instanceID, volumeName)
+		if _, err := os.Stat(staged); err != nil {
+			continue
+		}
+		if _, err := os.Stat(cacheDir); err == nil {
+			continue // a newer copy already exists
+		}
+		if err := os.MkdirAll(filepath.Dir(cacheDir), 0755); err != nil {
+			r.Logger.Warn("failed to restore incremental copy of volume %s: %v", volumeName, err)
+			continue
+		}
+		if err := os.Rename(staged, cacheDir); err != nil {
+			r.Logger.Warn("failed to restore incremental copy of volume %s: %v", volumeName, err)
+			continue
+		}
+		r.Logger.Info("restored incremental copy of volume %s from interrupted run", volumeName)
+	}
+}
+
+// cleanupContainerDumps removes /tmp/marina-<timestamp> dump directories from the running
+// containers of all configured database targets
+func (r *Runner) cleanupContainerDumps(ctx context.Context, schedules []model.InstanceBackupSchedule) int64 {
+	dbContainers := make(map[string]bool)
+	for _, schedule := range schedules {
+		for _, target := range schedule.Targets {
+			if target.Type == model.TargetDB {
+				dbContainers[target.Name] = true
+			}
+		}
+	}
+	if len(dbContainers) == 0 {
+		return 0
+	}
+
+	containers, err := r.Docker.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		r.Logger.Warn("failed to list containers: %v", err)
+		return 0
+	}
+
+	var reclaimed int64
+	for _, c := range containers {
+		if len(c.Names) == 0 || !dbContainers[strings.TrimPrefix(c.Names[0], "/")] {
+			continue
+		}
+		name := strings.TrimPrefix(c.Names[0], "/")
+
+		var output bytes.Buffer
+		if _, err := docker.ExecInContainerStream(ctx, r.Docker, c.ID, []string{"/bin/sh", "-c", cleanupDumpDirsScript}, &output); err != nil {
+			r.Logger.Warn("failed to clean up dump directories in %s: %v", name, err)
+			continue
+		}
+
+		var removed int
+		var size int64
+		for line := range strings.Lines(output.String()) {
+			kb, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+			if err != nil {
+				continue
+			}
+			removed++
+			size += kb * 1024
+		}
+		if removed > 0 {
+			r.Logger.Info("removed %d orphaned dump directories from container %s (%s)", removed, name, helpers.FormatBytes(size))
+			reclaimed += size
+		}
+	}
+	return reclaimed
+}
+
+// mountPoints returns all mount points visible to Marina's container
+func mountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Format: <id> <parent> <major:minor> <root> <mount point> ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mountPoint, err := strconv.Unquote(`"` + strings.ReplaceAll(fields[4], `"`, `\"`) + `"`)
+		if err != nil {
+			mountPoint = fields[4]
+		}
+		mounts = append(mounts, mountPoint)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read mountinfo: %w", err)
+	}
+	return mounts, nil
+}
+
+// mountsBelow returns the mount points at or below dir
+func mountsBelow(mounts []string, dir string) []string {
+	var result []string
+	for _, m := range mounts {
+		if m == dir || strings.HasPrefix(m, dir+"/") {
+			result = append(result, m)
+		}
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// controlPollInterval is how often the manager checks for commands from the API server
+const controlPollInterval = 2 * time.Second
+
+// errCancelled is the cancellation cause of a run cancelled on request
+var errCancelled = errors.New("cancelled on request")
+
+// WatchControlCommands processes commands stored by the API server until ctx is cancelled
+func (r *Runner) WatchControlCommands(ctx context.Context) {
+	if r.DB == nil {
+		return
+	}
+	ticker := time.NewTicker(controlPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.processControlCommands(ctx)
+		}
+	}
+}
+
+// processControlCommands executes all pending commands and records their outcome
+func (r *Runner) processControlCommands(ctx context.Context) {
+	commands, err := r.DB.GetPendingControlCommands(ctx)
+	if err != nil {
+		r.Logger.Warn("failed to read control commands: %v", err)
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.Type == model.CommandDryRun {
+			r.startDryRun(ctx, cmd)
+			continue
+		}
+
+		var err error
+		jobStatusID := cmd.JobStatusID
+		switch cmd.Type {
+		case model.CommandCancel:
+			err = r.CancelJob(ctx, cmd.JobStatusID)
+		case model.CommandRun:
+			jobStatusID, err = r.RunInstance(ctx, cmd.InstanceID, cmd.TargetIDs)
+		default:
+			err = fmt.Errorf("unknown command %q", cmd.Type)
+		}
+
+		result := "ok"
+		if err != nil {
+			result = err.Error()
+			r.Logger.Warn("control command %d (%s) failed: %v", cmd.ID, cmd.Type, err)
+		}
+		if err := r.DB.CompleteControlCommand(ctx, cmd.ID, jobStatusID, result, nil); err != nil {
+			r.Logger.Warn("failed to complete control command %d: %v", cmd.ID, err)
+		}
+	}
+}
+
+// CancelJob cancels a running or queued job. A running job is interrupted (restic and custom backup
+// containers are killed), runs its normal cleanups and is then marked cancelled; a queued job is
+// removed from the queue and marked cancelled right away.
+func (r *Runner) CancelJob(ctx context.Context, jobStatusID int) error {
+	r.mu.Lock()
+	if running, ok := r.running[jobStatusID]; ok {
+		running.cancel(errCancelled)
+		r.mu.Unlock()
+		r.Logger.Info("cancelling running job %d of instance %s", jobStatusID, running.instanceID)
+		return nil
+	}
+
+	index := slices.IndexFunc(r.queue, func(job queuedJob) bool { return job.jobStatusID == jobStatusID })
+	if index < 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("job %d is not running or queued", jobStatusID)
+	}
+	job := r.queue[index]
+	r.queue = slices.Delete(r.queue, index, index+1)
+	r.mu.Unlock()
+
+	// The job left the queue, so nothing else updates its status
+	if err := r.updateJobStatus(ctx, jobStatusID, func(s *model.JobStatus) {
+		now := time.Now()
+		s.Status = model.StatusCancelled
+		s.Message = errCancelled.Error()
+		s.LastCompletedAt = &now
+	}); err != nil {
+		r.Logger.Warn("failed to update job status: %v", err)
+	}
+	r.Logger.NewJobLogger(string(job.schedule.InstanceID), job.jobStatusID, job.jobStatusIID).Warn("queued run %s", errCancelled)
+	return nil
+}
+
+// RunInstance starts a manual run of an instance, limited to the given targets (all targets if none are
+// given), and returns its job ID. Targets of different schedule groups can be combined in one run.
+func (r *Runner) RunInstance(ctx context.Context, instanceID model.InstanceID, targetIDs []string) (int, error) {
+	r.mu.Lock()
+	var known []string
+	for _, job := range r.jobs {
+		if job.InstanceID == instanceID {
+			for _, target := range job.Targets {
+				known = append(known, target.ID)
+			}
+		}
+	}
+	schedule, ok := r.scheduleForTargets(instanceID, targetIDs)
+	r.mu.Unlock()
+
+	if len(known) == 0 {
+		return 0, fmt.Errorf("instance %s is not scheduled", instanceID)
+	}
+	for _, id := range targetIDs {
+		if !slices.Contains(known, id) {
+			return 0, fmt.Errorf("instance %s has no target %s", instanceID, id)
+		}
+	}
+	if !ok {
+		return 0, fmt.Errorf("instance %s has no targets to run", instanceID)
+	}
+	return r.TriggerNow(ctx, schedule)
+}
+
+// startDryRun executes a dry-run command in the background, so measuring volumes and reading the
+// repository does not hold up other commands. The command stays pending until its report is stored.
+func (r *Runner) startDryRun(ctx context.Context, cmd *model.ControlCommand) {
+	r.mu.Lock()
+	if r.dryRuns[cmd.ID] {
+		r.mu.Unlock()
+		return
+	}
+	r.dryRuns[cmd.ID] = true
+	schedules := slices.SortedFunc(maps.Values(r.jobs), func(a, b model.InstanceBackupSchedule) int {
+		return strings.Compare(a.Key(), b.Key())
+	})
+	r.mu.Unlock()
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.dryRuns, cmd.ID)
+			r.mu.Unlock()
+		}()
+
+		result := "ok"
+		report, err := r.DryRun(ctx, schedules, cmd.InstanceID)
+		if err != nil {
+			result = err.Error()
+			r.Logger.Warn("control command %d (%s) failed: %v", cmd.ID, cmd.Type, err)
+		}
+		if err := r.DB.CompleteControlCommand(ctx, cmd.ID, 0, result, report); err != nil {
+			r.Logger.Warn("failed to complete control command %d: %v", cmd.ID, err)
+		}
+	}()
+}
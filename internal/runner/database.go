@@ -3,23 +3,27 @@ package runner
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
 
+	"github.com/polarfoxDev/marina/internal/backend"
 	"github.com/polarfoxDev/marina/internal/docker"
 	"github.com/polarfoxDev/marina/internal/logging"
 	"github.com/polarfoxDev/marina/internal/model"
 )
 
-// stageDatabase prepares a database backup and returns the staged path and cleanup function
-func (r *Runner) stageDatabase(ctx context.Context, instanceID, timestamp string, target model.BackupTarget, jobLogger *logging.JobLogger) (string, cleanupFunc, error) {
+// resolveDatabaseTarget looks up the database container and detects the database kind.
+// Returns a copy of the target with ContainerID and DBKind filled in.
+func (r *Runner) resolveDatabaseTarget(ctx context.Context, target model.BackupTarget, jobLogger *logging.JobLogger) (model.BackupTarget, error) {
 	// Look up container from Docker to ensure it exists
 	containers, err := r.Docker.ContainerList(ctx, container.ListOptions{All: true})
 	if err != nil {
-		return "", nil, fmt.Errorf("list containers: %w", err)
+		return target, fmt.Errorf("list containers: %w", err)
 	}
 
 	var ctrInfo *container.Summary
@@ -36,41 +40,47 @@ func (r *Runner) stageDatabase(ctx context.Context, instanceID, timestamp string
 	}
 
 	if ctrInfo == nil {
-		return "", nil, fmt.Errorf("database container %q not found", target.Name)
+		return target, fmt.Errorf("database container %q not found", target.Name)
 	}
 
-	containerID := ctrInfo.ID
-	jobLogger.Debug("found container: %s (id: %s)", target.Name, containerID)
+	jobLogger.Debug("found container: %s (id: %s)", target.Name, ctrInfo.ID)
 
 	// Auto-detect database kind if not specified
 	dbKind := target.DBKind
 	if dbKind == "" {
 		dbKind = detectDBKind(ctrInfo.Image)
 		if dbKind == "" {
-			return "", nil, fmt.Errorf("could not auto-detect database type from image %q", ctrInfo.Image)
+			return target, fmt.Errorf("could not auto-detect database type from image %q", ctrInfo.Image)
 		}
 		jobLogger.Debug("auto-detected database kind: %s", dbKind)
 	}
 
+	resolvedTarget := target
+	resolvedTarget.ContainerID = ctrInfo.ID
+	resolvedTarget.DBKind = dbKind
+	return resolvedTarget, nil
+}
+
+// stageDatabase prepares a database backup and returns the staged path and cleanup function.
+// The resolved database kind is recorded in result.
+func (r *Runner) stageDatabase(ctx context.Context, instanceID, timestamp string, timeouts model.Timeouts, phases *phaseTimeline, target model.BackupTarget, result *model.JobTarget, jobLogger *logging.JobLogger) (string, cleanupFunc, error) {
+	resolvedTarget, err := r.resolveDatabaseTarget(ctx, target, jobLogger)
+	if err != nil {
+		return "", nil, err
+	}
+	result.DBKind = resolvedTarget.DBKind
+	containerID := resolvedTarget.ContainerID
+
 	// Execute pre-hook
 	if target.PreHook != "" {
-		jobLogger.Debug("executing pre-hook")
-		output, err := docker.ExecInContainer(ctx, r.Docker, containerID, []string{"/bin/sh", "-lc", target.PreHook})
-		if err != nil {
+		if err := r.runHook(ctx, phases, timeouts.Hook, target.ID, containerID, model.PhasePreHook, target.PreHook, jobLogger); err != nil {
 			return "", nil, fmt.Errorf("prehook: %w", err)
 		}
-		if output != "" {
-			jobLogger.Debug("pre-hook output: %s", output)
-		}
 		// Defer post-hook
 		defer func() {
 			if target.PostHook != "" {
-				jobLogger.Debug("executing post-hook")
-				output, err := docker.ExecInContainer(ctx, r.Docker, containerID, []string{"/bin/sh", "-lc", target.PostHook})
-				if err != nil {
+				if err := r.runHook(context.WithoutCancel(ctx), phases, timeouts.Hook, target.ID, containerID, model.PhasePostHook, target.PostHook, jobLogger); err != nil {
 					jobLogger.Warn("post-hook failed: %v", err)
-				} else if output != "" {
-					jobLogger.Debug("post-hook output: %s", output)
 				}
 			}
 		}()
@@ -89,30 +99,34 @@ func (r *Runner) stageDatabase(ctx context.Context, instanceID, timestamp string
 		return "", nil, fmt.Errorf("prepare host staging: %w", err)
 	}
 
-	// Build target with resolved values for dump command generation
-	resolvedTarget := target
-	resolvedTarget.ContainerID = containerID
-	resolvedTarget.DBKind = dbKind
-
 	// Build and execute dump command
 	dumpCmd, dumpFile, err := buildDumpCmd(resolvedTarget, containerDumpDir)
 	if err != nil {
 		return "", nil, err
 	}
 
+	// The dump timeout covers creating the dump and copying it out of the container
+	dumpCtx, cancelDump := withTimeout(ctx, "dump", target.ID, timeouts.Dump)
+	defer cancelDump()
+	endDump := phases.start(ctx, target.ID, model.PhaseDump)
+
 	jobLogger.Info("creating database dump")
-	output, err := docker.ExecInContainer(ctx, r.Docker, containerID, []string{"/bin/sh", "-lc", dumpCmd})
+	output, err := docker.ExecInContainer(dumpCtx, r.Docker, containerID, []string{"/bin/sh", "-lc", dumpCmd})
 	if err != nil {
-		return "", nil, fmt.Errorf("dump failed: %w", err)
+		err = fmt.Errorf("dump failed: %w", explainTimeout(dumpCtx, err))
+		endDump(err)
+		return "", nil, err
 	}
 	jobLogger.Debug("dump output: %s", output)
 
 	// Copy dump file from container
-	hostDumpPath, err := docker.CopyFileFromContainer(ctx, r.Docker, containerID, dumpFile, hostStagingDir, func(expected, written int64) {
+	hostDumpPath, err := docker.CopyFileFromContainer(dumpCtx, r.Docker, containerID, dumpFile, hostStagingDir, func(expected, written int64) {
 		if expected > 0 && expected != written {
 			jobLogger.Warn("copy warning: expected %d bytes, wrote %d", expected, written)
 		}
 	})
+	err = explainTimeout(dumpCtx, err)
+	endDump(err)
 	if err != nil {
 		return "", nil, err
 	}
@@ -120,7 +134,7 @@ func (r *Runner) stageDatabase(ctx context.Context, instanceID, timestamp string
 	// Create cleanup function
 	cleanup := func() {
 		// Clean up container dump directory
-		_, _ = docker.ExecInContainer(ctx, r.Docker, containerID, []string{"/bin/sh", "-lc", fmt.Sprintf("rm -rf %q", containerDumpDir)})
+		_, _ = docker.ExecInContainer(context.WithoutCancel(ctx), r.Docker, containerID, []string{"/bin/sh", "-lc", fmt.Sprintf("rm -rf %q", containerDumpDir)})
 		// Clean up host staging directory
 		_ = os.RemoveAll(hostStagingDir)
 	}
@@ -136,56 +150,184 @@ func (r *Runner) stageDatabase(ctx context.Context, instanceID, timestamp string
 	return hostDumpPath, cleanup, nil
 }
 
-// buildDumpCmd generates the appropriate dump command for a database target
+// streamDatabase pipes a database dump straight into restic ('restic backup --stdin') so it is
+// never written to disk. The dump ends up as db/<name>/<dump file> in a snapshot of its own.
+// If the dump fails, the incomplete snapshot is forgotten again. The resolved database kind is recorded in result.
+func (r *Runner) streamDatabase(ctx context.Context, dest *backend.ResticBackend, timeouts model.Timeouts, phases *phaseTimeline, target model.BackupTarget, result *model.JobTarget, jobLogger *logging.JobLogger) error {
+	resolvedTarget, err := r.resolveDatabaseTarget(ctx, target, jobLogger)
+	if err != nil {
+		return err
+	}
+	result.DBKind = resolvedTarget.DBKind
+	containerID := resolvedTarget.ContainerID
+
+	// Execute pre-hook
+	if target.PreHook != "" {
+		if err := r.runHook(ctx, phases, timeouts.Hook, target.ID, containerID, model.PhasePreHook, target.PreHook, jobLogger); err != nil {
+			return fmt.Errorf("prehook: %w", err)
+		}
+		// Defer post-hook
+		defer func() {
+			if target.PostHook != "" {
+				if err := r.runHook(context.WithoutCancel(ctx), phases, timeouts.Hook, target.ID, containerID, model.PhasePostHook, target.PostHook, jobLogger); err != nil {
+					jobLogger.Warn("post-hook failed: %v", err)
+				}
+			}
+		}()
+	}
+
+	dumpCmd, dumpFile, err := buildDumpCmd(resolvedTarget, "")
+	if err != nil {
+		return err
+	}
+	filename := path.Join("db", target.Name, dumpFile)
+
+	// Run the dump in the background, writing into restic's stdin
+	endStream := phases.start(ctx, target.ID, model.PhaseStream)
+	pr, pw := io.Pipe()
+	dumpOut := &countingWriter{w: pw}
+	dumpErrCh := make(chan error, 1)
+	dumpCtx, cancelDump := withTimeout(ctx, "dump", target.ID, timeouts.Dump)
+	defer cancelDump()
+	go func() {
+		stderr, err := docker.ExecInContainerStream(dumpCtx, r.Docker, containerID, []string{"/bin/sh", "-lc", dumpCmd}, dumpOut)
+		if stderr != "" {
+			jobLogger.Debug("dump output: %s", stderr)
+		}
+		// Always end the input cleanly so restic reports the snapshot it saved
+		_ = pw.Close()
+		dumpErrCh <- explainTimeout(dumpCtx, err)
+	}()
+
+	// Cancelling the run unblocks both ends of the pipe right away, without waiting for the
+	// exec stream or restic to notice
+	stopPipe := context.AfterFunc(ctx, func() { _ = pr.CloseWithError(ctx.Err()) })
+	defer stopPipe()
+
+	jobLogger.Info("streaming database dump into restic as %s", filename)
+	logs, backupErr := dest.BackupStdin(ctx, pr, filename, []string{fmt.Sprintf("%s:%s", target.Type, target.Name)})
+	// Unblock the dump if restic stopped reading early
+	_ = pr.Close()
+	dumpErr := <-dumpErrCh
+	jobLogger.Debug("%s", logs)
+
+	if dumpErr == nil && dumpOut.n == 0 {
+		dumpErr = fmt.Errorf("dump is empty")
+	}
+	if dumpErr != nil {
+		if id := backend.SnapshotIDFromOutput(logs); id != "" {
+			jobLogger.Warn("dump failed, forgetting incomplete snapshot %s", id)
+			if _, err := dest.ForgetSnapshot(context.WithoutCancel(ctx), id); err != nil {
+				jobLogger.Warn("failed to forget snapshot %s: %v", id, err)
+			}
+		}
+		err := fmt.Errorf("dump failed: %w", dumpErr)
+		endStream(err)
+		return err
+	}
+	if backupErr != nil {
+		err := fmt.Errorf("stream backup failed: %w", backupErr)
+		endStream(err)
+		return err
+	}
+	endStream(nil)
+	jobLogger.Debug("streamed %d bytes", dumpOut.n)
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// buildDumpCmd generates the appropriate dump command for a database target.
+// The dump is written to a file in dumpDir, or to stdout if dumpDir is empty (streaming);
+// output is the dump file path, or just its file name when streaming.
 func buildDumpCmd(t model.BackupTarget, dumpDir string) (cmd string, output string, err error) {
+	// redirect returns the shell redirection of the dump into file (none when streaming)
+	redirect := func(file string) string {
+		if dumpDir == "" {
+			return ""
+		}
+		return fmt.Sprintf(" > %q", file)
+	}
+
 	switch t.DBKind {
 	case "postgres":
 		file := filepath.Join(dumpDir, "dump.sql")
 		// Use pg_dumpall to dump all databases with postgres user
 		// PGPASSWORD env var should be set in container
 		args := stringsJoin(append([]string{"pg_dumpall", "-U", "postgres"}, t.DumpArgs...)...)
-		return fmt.Sprintf("%s > %q", args, file), file, nil
+		return args + redirect(file), file, nil
 	case "mysql":
 		file := filepath.Join(dumpDir, "dump.sql")
 		// Build dump command with automatic credential fallback
 		// If no dump.args provided, try MYSQL_ROOT_PASSWORD, then MYSQL_PASSWORD
 		if len(t.DumpArgs) == 0 {
+			if dumpDir == "" {
+				return streamCredentialDump("mysqldump", "MYSQL"), file, nil
+			}
 			cmd := fmt.Sprintf(`
-				mysqldump --single-transaction --all-databases -uroot -p"$MYSQL_ROOT_PASSWORD" > %q 2>/tmp/dump.err || \
+				mysqldump --single-transaction --all-databases -uroot -p"$MYSQL_ROOT_PASSWORD"%s 2>/tmp/dump.err || \
 				(echo "Root dump failed, trying MYSQL_USER..." >&2 && \
-				 mysqldump --single-transaction --all-databases -u"$MYSQL_USER" -p"$MYSQL_PASSWORD" > %q)
-			`, file, file)
+				 mysqldump --single-transaction --all-databases -u"$MYSQL_USER" -p"$MYSQL_PASSWORD"%s)
+			`, redirect(file), redirect(file))
 			return cmd, file, nil
 		}
 		// Use provided dump.args
 		baseArgs := []string{"mysqldump", "--single-transaction", "--all-databases"}
 		args := stringsJoin(append(baseArgs, t.DumpArgs...)...)
-		return fmt.Sprintf("%s > %q", args, file), file, nil
+		return args + redirect(file), file, nil
 	case "mariadb":
 		file := filepath.Join(dumpDir, "dump.sql")
 		// Build dump command with automatic credential fallback
 		// If no dump.args provided, try MARIADB_ROOT_PASSWORD, then MARIADB_PASSWORD
 		if len(t.DumpArgs) == 0 {
+			if dumpDir == "" {
+				return streamCredentialDump("mariadb-dump", "MARIADB"), file, nil
+			}
 			cmd := fmt.Sprintf(`
-				mariadb-dump --single-transaction --all-databases -uroot -p"$MARIADB_ROOT_PASSWORD" > %q 2>/tmp/dump.err || \
+				mariadb-dump --single-transaction --all-databases -uroot -p"$MARIADB_ROOT_PASSWORD"%s 2>/tmp/dump.err || \
 				(echo "Root dump failed, trying MARIADB_USER..." >&2 && \
-				 mariadb-dump --single-transaction --all-databases -u"$MARIADB_USER" -p"$MARIADB_PASSWORD" > %q)
-			`, file, file)
+				 mariadb-dump --single-transaction --all-databases -u"$MARIADB_USER" -p"$MARIADB_PASSWORD"%s)
+			`, redirect(file), redirect(file))
 			return cmd, file, nil
 		}
 		// Use provided dump.args
 		baseArgs := []string{"mariadb-dump", "--single-transaction", "--all-databases"}
 		args := stringsJoin(append(baseArgs, t.DumpArgs...)...)
-		return fmt.Sprintf("%s > %q", args, file), file, nil
+		return args + redirect(file), file, nil
 	case "mongo":
 		file := filepath.Join(dumpDir, "dump.archive")
 		args := stringsJoin(append([]string{"mongodump", "--archive"}, t.DumpArgs...)...)
-		return fmt.Sprintf("%s > %q", args, file), file, nil
+		return args + redirect(file), file, nil
 	default:
 		return "", "", fmt.Errorf("unsupported db kind %q", t.DBKind)
 	}
 }
 
+// streamCredentialDump returns a streamed MySQL/MariaDB dump that picks its credentials before
+// dumping: the root password if <envPrefix>_ROOT_PASSWORD is set (or no <envPrefix>_USER exists),
+// otherwise <envPrefix>_USER. Unlike file dumps there is no retry after a failed root dump, as its
+// partial output would already be in the snapshot.
+func streamCredentialDump(tool, envPrefix string) string {
+	return fmt.Sprintf(`
+				if [ -n "$%[2]s_ROOT_PASSWORD" ] || [ -z "$%[2]s_USER" ]; then
+					%[1]s --single-transaction --all-databases -uroot -p"$%[2]s_ROOT_PASSWORD"
+				else
+					echo "No %[2]s_ROOT_PASSWORD, using %[2]s_USER..." >&2
+					%[1]s --single-transaction --all-databases -u"$%[2]s_USER" -p"$%[2]s_PASSWORD"
+				fi
+			`, tool, envPrefix)
+}
+
 // detectDBKind attempts to detect the database type from the container image name
 func detectDBKind(imageName string) string {
 	// Convert to lowercase for case-insensitive matching
@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+func TestBuildDumpCmd_Stream(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      model.BackupTarget
+		wantFile    string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "postgres",
+			target:   model.BackupTarget{DBKind: "postgres"},
+			wantFile: "dump.sql",
+			contains: []string{"pg_dumpall -U postgres"},
+		},
+		{
+			name:        "mysql picks credentials before dumping",
+			target:      model.BackupTarget{DBKind: "mysql"},
+			wantFile:    "dump.sql",
+			contains:    []string{`if [ -n "$MYSQL_ROOT_PASSWORD" ] || [ -z "$MYSQL_USER" ]; then`, `-uroot -p"$MYSQL_ROOT_PASSWORD"`, `-u"$MYSQL_USER" -p"$MYSQL_PASSWORD"`},
+			notContains: []string{"Root dump failed", " > "},
+		},
+		{
+			name:        "mariadb picks credentials before dumping",
+			target:      model.BackupTarget{DBKind: "mariadb"},
+			wantFile:    "dump.sql",
+			contains:    []string{`if [ -n "$MARIADB_ROOT_PASSWORD" ] || [ -z "$MARIADB_USER" ]; then`, `-uroot -p"$MARIADB_ROOT_PASSWORD"`, `-u"$MARIADB_USER" -p"$MARIADB_PASSWORD"`},
+			notContains: []string{"Root dump failed", " > "},
+		},
+		{
+			name:        "mysql with dump args",
+			target:      model.BackupTarget{DBKind: "mysql", DumpArgs: []string{"-uapp", "-psecret"}},
+			wantFile:    "dump.sql",
+			contains:    []string{"mysqldump --single-transaction --all-databases -uapp -psecret"},
+			notContains: []string{"MYSQL_ROOT_PASSWORD", " > "},
+		},
+		{
+			name:     "mongo",
+			target:   model.BackupTarget{DBKind: "mongo"},
+			wantFile: "dump.archive",
+			contains: []string{"mongodump --archive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, file, err := buildDumpCmd(tt.target, "")
+			if err != nil {
+				t.Fatalf("buildDumpCmd() error = %v", err)
+			}
+			if file != tt.wantFile {
+				t.Errorf("file = %q, want %q", file, tt.wantFile)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(cmd, want) {
+					t.Errorf("command does not contain %q:\n%s", want, cmd)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(cmd, unwanted) {
+					t.Errorf("command contains %q:\n%s", unwanted, cmd)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildDumpCmd_FileKeepsFallback(t *testing.T) {
+	cmd, file, err := buildDumpCmd(model.BackupTarget{DBKind: "mysql"}, "/tmp/marina-x")
+	if err != nil {
+		t.Fatalf("buildDumpCmd() error = %v", err)
+	}
+	if file != "/tmp/marina-x/dump.sql" {
+		t.Errorf("file = %q", file)
+	}
+	// Each attempt overwrites the dump file, so a file dump may retry with the user credentials
+	if !strings.Contains(cmd, "Root dump failed, trying MYSQL_USER") || strings.Count(cmd, `> "/tmp/marina-x/dump.sql"`) != 2 {
+		t.Errorf("unexpected file dump command:\n%s", cmd)
+	}
+}
+
+// TestBuildDumpCmd_StreamWritesOneDump runs the streamed MySQL command with a fake mysqldump that
+// writes part of a dump and fails for root, and checks that only one dump reaches stdout
+func TestBuildDumpCmd_StreamWritesOneDump(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    -uroot) echo "partial root dump"; exit 1 ;;
+    -u*) echo "dump as ${arg#-u}"; exit 0 ;;
+  esac
+done
+`
+	if err := os.WriteFile(filepath.Join(dir, "mysqldump"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd, _, err := buildDumpCmd(model.BackupTarget{DBKind: "mysql"}, "")
+	if err != nil {
+		t.Fatalf("buildDumpCmd() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		env     []string
+		want    string
+		wantErr bool
+	}{
+		{name: "root password set", env: []string{"MYSQL_ROOT_PASSWORD=x", "MYSQL_USER=app"}, want: "partial root dump\n", wantErr: true},
+		{name: "only user credentials", env: []string{"MYSQL_USER=app", "MYSQL_PASSWORD=y"}, want: "dump as app\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh := exec.Command("/bin/sh", "-c", cmd)
+			sh.Env = append([]string{"PATH=" + dir + ":" + os.Getenv("PATH")}, tt.env...)
+			out, err := sh.Output()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(out) != tt.want {
+				t.Errorf("stdout = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
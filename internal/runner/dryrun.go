@@ -0,0 +1,220 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/helpers"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/manifest"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// DryRun reports what a run of an instance would do, covering all of the instance's schedules.
+// Volumes and database containers are resolved and staging sizes estimated like in a real run, but
+// no hooks or dumps are run, no containers are stopped, nothing is staged and the repository is only
+// read ('restic forget --dry-run' for the retention preview).
+func (r *Runner) DryRun(ctx context.Context, schedules []model.InstanceBackupSchedule, instanceID model.InstanceID) (*model.DryRunReport, error) {
+	r.mu.Lock()
+	dest, ok := r.BackupInstances[instanceID]
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("instance %s not found", instanceID)
+	}
+	var jobs []model.InstanceBackupSchedule
+	for _, job := range schedules {
+		if job.InstanceID == instanceID {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("instance %s is not scheduled", instanceID)
+	}
+
+	logger := r.Logger.NewJobLogger(string(instanceID), 0, 0)
+	logger.Info("dry run of instance %s", instanceID)
+
+	report := &model.DryRunReport{
+		InstanceID:       instanceID,
+		Backend:          string(dest.GetType()),
+		Image:            dest.GetImage(),
+		Retention:        jobs[0].Retention,
+		FreeStagingBytes: -1,
+		CreatedAt:        time.Now(),
+	}
+	resticBackend, isRestic := dest.(*backend.ResticBackend)
+	timestamp := report.CreatedAt.Format("20060102-150405")
+	instanceStagingDir := filepath.Join(stagingRoot, string(instanceID), timestamp)
+
+	for _, job := range jobs {
+		estimates, total := r.estimateStaging(ctx, job, logger)
+		report.EstimatedStagingBytes += total
+
+		var paths, tags []string
+		var streamed []model.DryRunTarget
+		direct := false
+		for _, target := range job.Targets {
+			item := model.DryRunTarget{
+				ID:       target.ID,
+				Type:     target.Type,
+				Name:     target.Name,
+				Schedule: job.Describe(),
+				Staging:  target.Staging,
+				Quiesce:  target.Quiesce,
+			}
+			if estimate, ok := estimates[target.ID]; ok {
+				item.EstimatedBytes = estimate
+			}
+
+			var err error
+			switch target.Type {
+			case model.TargetVolume:
+				err = r.dryRunVolume(ctx, instanceStagingDir, target, &item, logger.WithTarget(target.ID))
+			case model.TargetDB:
+				err = r.dryRunDatabase(ctx, instanceStagingDir, timestamp, target, &item, logger.WithTarget(target.ID))
+			default:
+				err = fmt.Errorf("unknown target type: %s", target.Type)
+			}
+			if err == nil && !isRestic && (target.Staging == model.StagingDirect || target.Staging == model.StagingStream) {
+				err = fmt.Errorf("staging: %s requires a restic repository", target.Staging)
+			}
+			if err != nil {
+				item.Error = err.Error()
+				report.Problems = append(report.Problems, fmt.Sprintf("%s: %v", target.ID, err))
+			} else if target.Staging == model.StagingStream {
+				streamed = append(streamed, item)
+			} else {
+				paths = append(paths, item.Paths...)
+				tags = append(tags, fmt.Sprintf("%s:%s", target.Type, target.Name))
+				direct = direct || target.Staging == model.StagingDirect
+			}
+			report.Targets = append(report.Targets, item)
+		}
+
+		if !isRestic {
+			continue
+		}
+		// Same order as runInstanceBackup: one snapshot of all staged paths (with the manifest),
+		// then one snapshot per streamed dump, then retention
+		if len(paths) > 0 {
+			if slices.ContainsFunc(paths, func(p string) bool { return strings.HasPrefix(p, instanceStagingDir+"/") }) {
+				paths = append(paths, filepath.Join(instanceStagingDir, manifest.FileName))
+			}
+			command := strings.Join(resticBackend.BackupCommand(paths, deduplicate(tags)), " ")
+			if direct {
+				command += " (in a helper container with the direct volumes mounted read-only)"
+			}
+			report.Commands = append(report.Commands, command)
+		}
+		for _, item := range streamed {
+			command := resticBackend.BackupStdinCommand(item.Paths[0], []string{fmt.Sprintf("%s:%s", item.Type, item.Name)})
+			report.Commands = append(report.Commands, item.DumpCommand+" | "+strings.Join(command, " "))
+		}
+		report.Commands = append(report.Commands, strings.Join(resticBackend.RetentionCommand(job.Retention.KeepDaily, job.Retention.KeepWeekly, job.Retention.KeepMonthly), " "))
+	}
+
+	if free, err := helpers.FreeDiskSpace(stagingRoot); err != nil {
+		logger.Warn("could not determine free staging space: %v", err)
+	} else {
+		report.FreeStagingBytes = free
+		if report.EstimatedStagingBytes > 0 {
+			if err := checkStagingSpace(report.EstimatedStagingBytes, free); err != nil {
+				report.Problems = append(report.Problems, err.Error())
+			}
+		}
+	}
+
+	if isRestic {
+		preview, err := resticBackend.PreviewRetention(ctx, report.Retention.KeepDaily, report.Retention.KeepWeekly, report.Retention.KeepMonthly)
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("retention preview: %v", err))
+		}
+		report.RetentionPreview = preview
+	}
+
+	logger.Info("dry run of instance %s finished (%d targets, %d problems)", instanceID, len(report.Targets), len(report.Problems))
+	return report, nil
+}
+
+// dryRunVolume resolves a volume target like stageVolume and records the containers a run would
+// quiesce or run hooks in, and the paths it would pass to the backend
+func (r *Runner) dryRunVolume(ctx context.Context, instanceStagingDir string, target model.BackupTarget, item *model.DryRunTarget, jobLogger *logging.JobLogger) error {
+	if _, err := r.Docker.VolumeInspect(ctx, target.Name); err != nil {
+		return fmt.Errorf("volume %q not found: %w", target.Name, err)
+	}
+
+	if target.PreHook != "" || target.PostHook != "" || quiesceEnabled(target.Quiesce) {
+		attachedCtrs, err := r.attachedContainers(ctx, target.Name)
+		if err != nil {
+			return err
+		}
+		if target.PreHook != "" && len(attachedCtrs) > 0 {
+			item.HookContainer = r.containerName(ctx, attachedCtrs[0])
+		}
+		if quiesceEnabled(target.Quiesce) {
+			selected, err := r.containersToQuiesce(ctx, target, attachedCtrs, jobLogger)
+			if err != nil {
+				return err
+			}
+			for _, ctr := range selected {
+				item.QuiescedContainers = append(item.QuiescedContainers, r.containerName(ctx, ctr))
+			}
+		}
+	}
+
+	if target.Staging == model.StagingDirect {
+		item.Paths = directVolumePaths(target.Name, target.Paths)
+		return nil
+	}
+	volumeDir := filepath.Join(instanceStagingDir, "volume", target.Name)
+	for _, p := range target.Paths {
+		item.Paths = append(item.Paths, filepath.Join(volumeDir, strings.TrimPrefix(p, "/")))
+	}
+	return nil
+}
+
+// dryRunDatabase resolves a database target like stageDatabase and records the dump command
+// and the path of the dump passed to the backend
+func (r *Runner) dryRunDatabase(ctx context.Context, instanceStagingDir, timestamp string, target model.BackupTarget, item *model.DryRunTarget, jobLogger *logging.JobLogger) error {
+	resolvedTarget, err := r.resolveDatabaseTarget(ctx, target, jobLogger)
+	if err != nil {
+		return err
+	}
+	item.ContainerID = resolvedTarget.ContainerID
+	item.DBKind = resolvedTarget.DBKind
+	if target.PreHook != "" {
+		item.HookContainer = target.Name
+	}
+
+	if target.Staging == model.StagingStream {
+		dumpCmd, dumpFile, err := buildDumpCmd(resolvedTarget, "")
+		if err != nil {
+			return err
+		}
+		item.DumpCommand = strings.TrimSpace(dumpCmd)
+		item.Paths = []string{path.Join("db", target.Name, dumpFile)}
+		return nil
+	}
+	dumpCmd, dumpFile, err := buildDumpCmd(resolvedTarget, fmt.Sprintf("/tmp/marina-%s", timestamp))
+	if err != nil {
+		return err
+	}
+	item.DumpCommand = strings.TrimSpace(dumpCmd)
+	item.Paths = []string{filepath.Join(instanceStagingDir, "db", target.Name, filepath.Base(dumpFile))}
+	return nil
+}
+
+// containerName returns the name of a container, or its ID if it cannot be inspected
+func (r *Runner) containerName(ctx context.Context, containerID string) string {
+	info, err := r.Docker.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return containerID
+	}
+	return strings.TrimPrefix(info.Name, "/")
+}
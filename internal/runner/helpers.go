@@ -77,6 +77,31 @@ func validateFileSize(paths []string, jobLogger *logging.JobLogger) error {
 	return fmt.Errorf("all %d file(s) are empty (0 bytes) - backup likely failed silently", totalFiles)
 }
 
+// stagedSize returns the total size of all regular files in the given paths
+func stagedSize(paths []string) (int64, error) {
+	var total int64
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return fmt.Errorf("get file info for %s: %w", p, err)
+			}
+			total += info.Size()
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("error walking %s: %w", path, err)
+		}
+	}
+	return total, nil
+}
+
 // deduplicate removes duplicate strings from a slice
 func deduplicate(slice []string) []string {
 	seen := make(map[string]bool)
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/polarfoxDev/marina/internal/helpers"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/manifest"
+)
+
+// writeManifest writes a checksum manifest of the staged paths below the staging directory
+// (<staging dir>/marina-manifest.json) and returns its path. Paths outside the staging directory
+// (volumes read in place) are not listed. A manifest that cannot be written only logs a warning;
+// the backup continues without it.
+// A copy is kept next to the run's staging directory (/backup/<instance>/marina-manifest.json), so
+// the next run reuses the checksums of files whose size, mode and modification time are unchanged.
+func writeManifest(stagingDir string, paths []string, logger *logging.JobLogger) (string, bool) {
+	var staged []string
+	for _, path := range paths {
+		if strings.HasPrefix(path, stagingDir+"/") {
+			staged = append(staged, path)
+		}
+	}
+	if len(staged) == 0 {
+		return "", false
+	}
+
+	previousPath := filepath.Join(filepath.Dir(stagingDir), manifest.FileName)
+	previous, err := manifest.Load(previousPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("ignoring previous manifest: %v", err)
+		}
+		previous = nil
+	}
+
+	m, err := manifest.Build(stagingDir, staged, previous)
+	if err != nil {
+		logger.Warn("failed to build manifest: %v", err)
+		return "", false
+	}
+	path := filepath.Join(stagingDir, manifest.FileName)
+	if err := m.Write(path); err != nil {
+		logger.Warn("failed to write manifest: %v", err)
+		return "", false
+	}
+
+	var total int64
+	for _, entry := range m.Entries {
+		total += entry.Size
+	}
+	logger.Info("wrote manifest of %d entries (%s, %d checksums reused)", len(m.Entries), helpers.FormatBytes(total), m.Reused)
+	if err := m.Write(previousPath); err != nil {
+		logger.Warn("failed to keep manifest for the next run: %v", err)
+	}
+	return path, true
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/manifest"
+)
+
+func TestWriteManifest_KeepsCopyForNextRun(t *testing.T) {
+	logger, err := logging.New(openTestDB(t).GetDB(), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobLogger := logger.NewJobLogger("app", 1, 1)
+	instanceDir := t.TempDir()
+
+	data := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(data, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stageRun stages the unchanged file into a new timestamp directory, keeping its modification time like cp -a
+	stageRun := func(timestamp string) (string, string) {
+		stagingDir := filepath.Join(instanceDir, timestamp)
+		path := filepath.Join(stagingDir, "volume", "data", "data.txt")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+			t.Fatal(err)
+		}
+		return stagingDir, filepath.Join(stagingDir, "volume", "data")
+	}
+
+	stagingDir, staged := stageRun("20260101-030000")
+	if _, ok := writeManifest(stagingDir, []string{staged}, jobLogger); !ok {
+		t.Fatal("manifest not written")
+	}
+	if err := os.RemoveAll(stagingDir); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := manifest.Load(filepath.Join(instanceDir, manifest.FileName))
+	if err != nil {
+		t.Fatalf("no manifest kept for the next run: %v", err)
+	}
+	// Mark the kept checksum to see whether the next run reuses it
+	for i := range kept.Entries {
+		if kept.Entries[i].SHA256 != "" {
+			kept.Entries[i].SHA256 = "cached"
+		}
+	}
+	if err := kept.Write(filepath.Join(instanceDir, manifest.FileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	stagingDir, staged = stageRun("20260102-030000")
+	path, ok := writeManifest(stagingDir, []string{staged}, jobLogger)
+	if !ok {
+		t.Fatal("manifest not written")
+	}
+	m, err := manifest.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range m.Entries {
+		if entry.Path == "volume/data/data.txt" && entry.SHA256 != "cached" {
+			t.Errorf("checksum of unchanged file = %q, want the one of the previous run", entry.SHA256)
+		}
+	}
+}
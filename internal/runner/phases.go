@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// phaseTimeline records the start and end of the phases of a run in job_phases.
+// A nil timeline records nothing.
+type phaseTimeline struct {
+	r           *Runner
+	jobStatusID int
+	logger      *logging.JobLogger
+}
+
+// newPhaseTimeline returns the timeline of a job (nil without a database or job record)
+func (r *Runner) newPhaseTimeline(jobStatusID int, logger *logging.JobLogger) *phaseTimeline {
+	if r.DB == nil || jobStatusID == 0 {
+		return nil
+	}
+	return &phaseTimeline{r: r, jobStatusID: jobStatusID, logger: logger}
+}
+
+// start begins a phase of a target ("" for the whole job). The returned function ends it with
+// the phase's outcome and stores it (best effort - also after the run was cancelled).
+func (t *phaseTimeline) start(ctx context.Context, targetID string, phase model.JobPhaseName) func(error) {
+	if t == nil {
+		return func(error) {}
+	}
+	startedAt := time.Now()
+	return func(err error) {
+		endedAt := time.Now()
+		record := model.JobPhase{
+			JobStatusID: t.jobStatusID,
+			TargetID:    targetID,
+			Phase:       phase,
+			StartedAt:   startedAt,
+			EndedAt:     endedAt,
+			DurationMs:  endedAt.Sub(startedAt).Milliseconds(),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := t.r.DB.RecordJobPhase(dbCtx, record); err != nil {
+			t.logger.Warn("failed to record %s phase: %v", phase, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/docker"
+	"github.com/polarfoxDev/marina/internal/helpers"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// stagingRoot is the staging mount inside Marina's container
+const stagingRoot = "/backup"
+
+// usesStagingSpace reports whether a target writes its data into the staging directory
+func usesStagingSpace(target model.BackupTarget) bool {
+	return target.Staging == "" || target.Staging == model.StagingCopy || target.Staging == model.StagingIncremental
+}
+
+// incrementalCopyExists reports whether a volume already has a persistent incremental staging copy
+func incrementalCopyExists(instanceID, volumeName string) bool {
+	_, err := os.Stat(docker.IncrementalCacheDir(instanceID, volumeName))
+	return err == nil
+}
+
+// preflightStaging estimates how much staging space the targets of a run need and fails early
+// if the staging mount does not have enough free space (plus 10% headroom). Volumes are measured
+// with 'du'; targets that cannot be measured (databases, failed measurements) fall back to their
+// staged size from the previous run. Incremental copies only need room for the growth since
+// the previous run. Returns the estimate per target ID (-1 if unknown).
+func (r *Runner) preflightStaging(ctx context.Context, job model.InstanceBackupSchedule, logger *logging.JobLogger) (map[string]int64, error) {
+	estimates, total := r.estimateStaging(ctx, job, logger)
+	if total == 0 {
+		return estimates, nil
+	}
+
+	free, err := helpers.FreeDiskSpace(stagingRoot)
+	if err != nil {
+		logger.Warn("could not determine free staging space: %v", err)
+		return estimates, nil
+	}
+	if err := checkStagingSpace(total, free); err != nil {
+		return estimates, err
+	}
+	logger.Info("preflight: estimated staging size %s, %s free on %s", helpers.FormatBytes(total), helpers.FormatBytes(free), stagingRoot)
+	return estimates, nil
+}
+
+// checkStagingSpace fails if the estimated staging size plus 10% headroom exceeds the free space
+func checkStagingSpace(total, free int64) error {
+	required := total + total/10
+	if required > free {
+		return fmt.Errorf("not enough free space for staging: about %s needed (%s estimated plus 10%% headroom), only %s free on %s",
+			helpers.FormatBytes(required), helpers.FormatBytes(total), helpers.FormatBytes(free), stagingRoot)
+	}
+	return nil
+}
+
+// estimateStaging estimates the staging space of each target of a run that writes into the staging
+// directory (see preflightStaging). Returns the estimates by target ID (-1 if unknown) and their sum.
+func (r *Runner) estimateStaging(ctx context.Context, job model.InstanceBackupSchedule, logger *logging.JobLogger) (map[string]int64, int64) {
+	var previous map[string]model.StagingSize
+	if r.DB != nil {
+		sizes, err := r.DB.GetStagingSizes(ctx, string(job.InstanceID))
+		if err != nil {
+			logger.Warn("failed to load staging sizes of previous run: %v", err)
+		}
+		previous = sizes
+	}
+
+	estimates := make(map[string]int64)
+	var total int64
+	for _, target := range job.Targets {
+		if !usesStagingSpace(target) {
+			continue
+		}
+
+		estimate := int64(-1)
+		if target.Type == model.TargetVolume {
+			size, err := docker.EstimateVolumeSize(ctx, r.Docker, job.Helper, target.Name, target.Paths)
+			if err != nil {
+				logger.Debug("could not measure volume %s: %v", target.Name, err)
+			} else {
+				estimate = size
+			}
+		}
+		if prev, ok := previous[target.ID]; estimate < 0 && ok && prev.StagedBytes > 0 {
+			estimate = prev.StagedBytes
+		}
+		// An existing incremental copy only grows by the difference
+		if prev, ok := previous[target.ID]; target.Staging == model.StagingIncremental && estimate > 0 && ok && incrementalCopyExists(string(job.InstanceID), target.Name) {
+			estimate = max(0, estimate-prev.StagedBytes)
+		}
+
+		estimates[target.ID] = estimate
+		if estimate < 0 {
+			logger.Debug("no staging size estimate for %s", target.ID)
+			continue
+		}
+		logger.Debug("estimated staging size for %s: %s", target.ID, helpers.FormatBytes(estimate))
+		total += estimate
+	}
+	return estimates, total
+}
+
+// recordStagingSize stores the preflight estimate next to the actual staged size of a target
+// and returns the staged size (0 if it could not be measured)
+func (r *Runner) recordStagingSize(ctx context.Context, instanceID model.InstanceID, jobStatusID int, targetID string, estimate int64, stagedPaths []string, logger *logging.JobLogger) int64 {
+	staged, err := stagedSize(stagedPaths)
+	if err != nil {
+		logger.Warn("failed to measure staged size: %v", err)
+		return 0
+	}
+	if estimate >= 0 {
+		logger.Debug("staged %s (estimated %s)", helpers.FormatBytes(staged), helpers.FormatBytes(estimate))
+	} else {
+		logger.Debug("staged %s", helpers.FormatBytes(staged))
+	}
+	if r.DB == nil {
+		return staged
+	}
+
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := r.DB.RecordStagingSize(dbCtx, model.StagingSize{
+		InstanceID:     instanceID,
+		TargetID:       targetID,
+		JobStatusID:    jobStatusID,
+		EstimatedBytes: estimate,
+		StagedBytes:    staged,
+	}); err != nil {
+		logger.Warn("failed to record staging size: %v", err)
+	}
+	return staged
+}
@@ -0,0 +1,351 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// errSuperseded is the cancellation cause of a run replaced by a newer one (overlap: cancel-previous)
+var errSuperseded = errors.New("cancelled by a newer run (overlap: cancel-previous)")
+
+// errShuttingDown is returned for runs requested after Stop
+var errShuttingDown = errors.New("marina is shutting down")
+
+// queuedJob is a run waiting for a free slot
+type queuedJob struct {
+	jobStatusID  int
+	jobStatusIID int
+	schedule     model.InstanceBackupSchedule
+}
+
+// runningJob is a run that holds a slot
+type runningJob struct {
+	key          string // schedule key (instance@cron)
+	instanceID   model.InstanceID
+	jobStatusIID int
+	cancel       context.CancelCauseFunc
+}
+
+// SetConcurrencyLimits sets how many jobs may run at the same time, overall and per backend type.
+// Zero means unlimited. Runs above the limits wait in the queue with status queued.
+func (r *Runner) SetConcurrencyLimits(maxJobs int, perBackend map[backend.BackendType]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxJobs = maxJobs
+	r.backendLimits = make(map[backend.BackendType]int, len(perBackend))
+	for backendType, limit := range perBackend {
+		r.backendLimits[backendType] = limit
+	}
+	r.dispatchLocked()
+}
+
+// ResumeQueuedJobs puts jobs that were still queued when Marina stopped back into the queue,
+// in their original order. Jobs of instances that are no longer configured are marked aborted.
+// Must be called after SyncBackups.
+func (r *Runner) ResumeQueuedJobs(ctx context.Context) error {
+	if r.DB == nil {
+		return nil
+	}
+	queued, err := r.DB.GetQueuedJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var orphaned []int
+	r.mu.Lock()
+	for _, status := range queued {
+		schedule, ok := r.scheduleForTargets(status.InstanceID, status.TargetIDs)
+		if !ok {
+			orphaned = append(orphaned, status.ID)
+			continue
+		}
+		r.queue = append(r.queue, queuedJob{jobStatusID: status.ID, jobStatusIID: status.IID, schedule: schedule})
+		r.Logger.NewJobLogger(string(status.InstanceID), status.ID, status.IID).Info("resuming queued run after restart")
+	}
+	r.dispatchLocked()
+	r.mu.Unlock()
+
+	for _, jobStatusID := range orphaned {
+		if err := r.updateJobStatus(ctx, jobStatusID, func(s *model.JobStatus) {
+			s.Status = model.StatusAborted
+			s.Message = "instance or targets are no longer configured"
+		}); err != nil {
+			r.Logger.Warn("failed to update job status: %v", err)
+		}
+	}
+	return nil
+}
+
+// enqueue creates the job status record for a run of the instance, applies the instance's overlap
+// policy and starts the run as soon as the concurrency limits allow. A run that has to wait is
+// persisted with status queued; a run rejected by the overlap policy is recorded as skipped.
+// The trigger describes why the run was requested and is written to the job log. Returns the job status ID.
+func (r *Runner) enqueue(ctx context.Context, schedule model.InstanceBackupSchedule, trigger string) (int, error) {
+	r.mu.Lock()
+	stopping := r.stopping
+	r.mu.Unlock()
+	if stopping {
+		return 0, errShuttingDown
+	}
+
+	var jobStatusID, jobStatusIID int
+	if r.DB != nil {
+		jobStatus, err := r.DB.ScheduleNewJob(ctx, string(schedule.InstanceID), targetIDs(schedule.Targets))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create job status: %w", err)
+		}
+		jobStatusID = jobStatus.ID
+		jobStatusIID = jobStatus.IID
+	}
+	instanceLogger := r.Logger.NewJobLogger(string(schedule.InstanceID), jobStatusID, jobStatusIID)
+	instanceLogger.Info("%s requested (schedule %s)", trigger, schedule.Describe())
+
+	// Job status records are written after releasing r.mu, so dispatching never waits for the database
+	r.mu.Lock()
+	var superseded []int
+	if previous := r.activeRunLocked(schedule.Key()); previous != "" {
+		switch schedule.Overlap {
+		case model.OverlapQueue:
+			instanceLogger.Info("previous run %s is still active, waiting for it to finish", previous)
+		case model.OverlapCancelPrevious:
+			instanceLogger.Warn("previous run %s is still active, cancelling it", previous)
+			superseded = r.supersedeLocked(schedule.Key())
+		default:
+			r.mu.Unlock()
+			reason := fmt.Sprintf("previous run %s is still active", previous)
+			r.markSkipped(ctx, jobStatusID, reason)
+			instanceLogger.Warn("run skipped: %s", reason)
+			return jobStatusID, nil
+		}
+	}
+
+	r.queue = append(r.queue, queuedJob{jobStatusID: jobStatusID, jobStatusIID: jobStatusIID, schedule: schedule})
+	r.dispatchLocked()
+	waiting := slices.ContainsFunc(r.queue, func(j queuedJob) bool { return j.jobStatusID == jobStatusID })
+	running, queued := len(r.running), len(r.queue)
+	r.mu.Unlock()
+
+	for _, id := range superseded {
+		r.markSkipped(ctx, id, fmt.Sprintf("superseded by run #%d", jobStatusIID))
+	}
+
+	// Still waiting: persist it, so the run survives a restart. The run may have been dispatched
+	// (or skipped) since r.mu was released, so only a run that is still scheduled is marked queued.
+	if waiting {
+		if err := r.updateJobStatus(ctx, jobStatusID, func(s *model.JobStatus) {
+			if s.Status == model.StatusScheduled {
+				s.Status = model.StatusQueued
+			}
+		}); err != nil {
+			r.Logger.Warn("failed to update job status: %v", err)
+		}
+		instanceLogger.Info("queued: %d job(s) running, %d waiting", running, queued)
+	}
+	return jobStatusID, nil
+}
+
+// markSkipped records a run that will not be executed as skipped, with the reason
+func (r *Runner) markSkipped(ctx context.Context, jobStatusID int, reason string) {
+	if err := r.updateJobStatus(ctx, jobStatusID, func(s *model.JobStatus) {
+		now := time.Now()
+		s.Status = model.StatusSkipped
+		s.Message = reason
+		s.LastCompletedAt = &now
+	}); err != nil {
+		r.Logger.Warn("failed to update job status: %v", err)
+	}
+}
+
+// activeRunLocked describes the running or queued run of a schedule group ("" if there is none). Requires r.mu.
+func (r *Runner) activeRunLocked(key string) string {
+	for _, job := range r.running {
+		if job.key == key {
+			return fmt.Sprintf("#%d (running)", job.jobStatusIID)
+		}
+	}
+	for _, job := range r.queue {
+		if job.schedule.Key() == key {
+			return fmt.Sprintf("#%d (queued)", job.jobStatusIID)
+		}
+	}
+	return ""
+}
+
+// supersedeLocked cancels the running run of a schedule group and removes its queued runs, returning
+// their job status IDs for the caller to mark skipped once r.mu is released. The new run starts once
+// the cancelled run has cleaned up. Requires r.mu.
+func (r *Runner) supersedeLocked(key string) []int {
+	for _, job := range r.running {
+		if job.key == key {
+			job.cancel(errSuperseded)
+		}
+	}
+	var removed []int
+	r.queue = slices.DeleteFunc(r.queue, func(job queuedJob) bool {
+		if job.schedule.Key() != key {
+			return false
+		}
+		removed = append(removed, job.jobStatusID)
+		return true
+	})
+	return removed
+}
+
+// dispatchLocked starts queued runs in order while slots are free. A run whose backend type is at
+// its limit, or whose instance is still running (runs of the same instance, including other schedule
+// groups, never run at the same time), does not block the runs behind it. Requires r.mu.
+func (r *Runner) dispatchLocked() {
+	if r.stopping {
+		// Queued runs stay queued and are resumed after the restart (ResumeQueuedJobs)
+		return
+	}
+	for i := 0; i < len(r.queue); {
+		if r.maxJobs > 0 && len(r.running) >= r.maxJobs {
+			return
+		}
+		job := r.queue[i]
+		backendType := r.backendType(job.schedule.InstanceID)
+		if limit := r.backendLimits[backendType]; limit > 0 && r.runningPerBackend[backendType] >= limit {
+			i++
+			continue
+		}
+		if r.instanceRunningLocked(job.schedule.InstanceID) {
+			i++
+			continue
+		}
+		r.queue = slices.Delete(r.queue, i, i+1)
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		r.running[job.jobStatusID] = &runningJob{key: job.schedule.Key(), instanceID: job.schedule.InstanceID, jobStatusIID: job.jobStatusIID, cancel: cancel}
+		r.runningPerBackend[backendType]++
+		r.runs.Add(1)
+		go r.execute(ctx, job, backendType)
+	}
+}
+
+// instanceRunningLocked reports whether a run of the instance holds a slot. Requires r.mu.
+func (r *Runner) instanceRunningLocked(instanceID model.InstanceID) bool {
+	for _, job := range r.running {
+		if job.instanceID == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleForTargets returns the schedule group of an instance that covers exactly the given targets.
+// Without an exact match (targets regrouped since, or no target IDs recorded) it returns the instance's
+// first group restricted to the configured targets among the IDs (all targets if none are given).
+func (r *Runner) scheduleForTargets(instanceID model.InstanceID, ids []string) (model.InstanceBackupSchedule, bool) {
+	var groups []model.InstanceBackupSchedule
+	var allTargets []model.BackupTarget
+	for _, key := range slices.Sorted(maps.Keys(r.jobs)) {
+		if job := r.jobs[key]; job.InstanceID == instanceID {
+			if slices.Equal(targetIDs(job.Targets), ids) {
+				return job, true
+			}
+			groups = append(groups, job)
+			allTargets = append(allTargets, job.Targets...)
+		}
+	}
+	if len(groups) == 0 {
+		return model.InstanceBackupSchedule{}, false
+	}
+
+	schedule := groups[0]
+	schedule.Targets = nil
+	for _, target := range allTargets {
+		if len(ids) == 0 || slices.Contains(ids, target.ID) {
+			schedule.Targets = append(schedule.Targets, target)
+		}
+	}
+	return schedule, len(schedule.Targets) > 0
+}
+
+// targetIDs returns the IDs of the targets
+func targetIDs(targets []model.BackupTarget) []string {
+	ids := make([]string, 0, len(targets))
+	for _, target := range targets {
+		ids = append(ids, target.ID)
+	}
+	return ids
+}
+
+// backendType returns the backend type of an instance ("" if the instance is unknown)
+func (r *Runner) backendType(instanceID model.InstanceID) backend.BackendType {
+	if dest, ok := r.BackupInstances[instanceID]; ok {
+		return dest.GetType()
+	}
+	return ""
+}
+
+// execute runs a dequeued job and frees its slot afterwards
+func (r *Runner) execute(ctx context.Context, job queuedJob, backendType backend.BackendType) {
+	defer r.runs.Done()
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if running, ok := r.running[job.jobStatusID]; ok {
+			running.cancel(nil)
+			delete(r.running, job.jobStatusID)
+		}
+		r.runningPerBackend[backendType]--
+		r.closeRetiredLocked(job.schedule.InstanceID)
+		r.dispatchLocked()
+	}()
+
+	ctx, cancel := withTimeout(ctx, "job", "", job.schedule.Timeouts.Job)
+	defer cancel()
+
+	// Create instance-level logger with job status IDs
+	instanceLogger := r.Logger.NewJobLogger(string(job.schedule.InstanceID), job.jobStatusID, job.jobStatusIID)
+
+	instanceLogger.Info("instance backup started (%d targets)", len(job.schedule.Targets))
+	startTime := time.Now()
+
+	err := r.runBackup(ctx, job.schedule, job.jobStatusID, instanceLogger)
+	if cause := context.Cause(ctx); err != nil && (errors.Is(cause, errSuperseded) || errors.Is(cause, errCancelled) || errors.Is(cause, errShutdown)) {
+		// Replaced by a newer run, cancelled on request or aborted on shutdown: the cleanups have run, record why it stopped
+		status := model.StatusAborted
+		if errors.Is(cause, errCancelled) {
+			status = model.StatusCancelled
+		}
+		if err := r.updateJobStatus(context.WithoutCancel(ctx), job.jobStatusID, func(s *model.JobStatus) {
+			now := time.Now()
+			s.Status = status
+			s.Message = cause.Error()
+			s.LastCompletedAt = &now
+		}); err != nil {
+			r.Logger.Warn("failed to update job status: %v", err)
+		}
+		instanceLogger.Warn("instance backup %s", cause)
+		return
+	}
+	if timeoutErr, ok := timeoutCause(ctx); err != nil && ok {
+		// The status updates of the run itself failed with the expired context
+		if err := r.updateJobStatus(context.WithoutCancel(ctx), job.jobStatusID, func(s *model.JobStatus) {
+			now := time.Now()
+			s.Status = model.StatusFailed
+			s.Message = timeoutErr.Error()
+			s.LastCompletedAt = &now
+		}); err != nil {
+			r.Logger.Warn("failed to update job status: %v", err)
+		}
+		instanceLogger.Error("instance backup failed: %v", timeoutErr)
+		return
+	}
+	if err != nil {
+		instanceLogger.Error("instance backup failed: %v", err)
+	} else {
+		duration := time.Since(startTime)
+		instanceLogger.Info("instance backup completed (duration: %v)", duration)
+		r.completeUpstream(job.schedule.InstanceID, job.jobStatusIID)
+	}
+}
@@ -0,0 +1,289 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+func TestQueue_ConcurrencyLimits(t *testing.T) {
+	db := openTestDB(t)
+	instances := map[model.InstanceID]backend.Backend{
+		"restic-a": newFakeBackend(backend.BackendTypeRestic),
+		"restic-b": newFakeBackend(backend.BackendTypeRestic),
+		"custom-a": newFakeBackend(backend.BackendTypeCustomImage),
+		"custom-b": newFakeBackend(backend.BackendTypeCustomImage),
+	}
+	r, runs := newTestRunner(t, db, instances,
+		testSchedule("restic-a"), testSchedule("restic-b"), testSchedule("custom-a"), testSchedule("custom-b"))
+	r.SetConcurrencyLimits(2, map[backend.BackendType]int{backend.BackendTypeRestic: 1})
+
+	trigger(t, r, "restic-a")
+	resticB := trigger(t, r, "restic-b")
+	trigger(t, r, "custom-a")
+	customB := trigger(t, r, "custom-b")
+
+	// restic-b waits for the restic limit, custom-b for the global limit
+	started := runs.expectStarted(t, "restic-a", "custom-a")
+	runs.expectNoStart(t)
+	waitForStatus(t, db, resticB, model.StatusQueued)
+	waitForStatus(t, db, customB, model.StatusQueued)
+
+	// Finishing the restic run frees a restic slot and a global slot: restic-b goes first
+	// (it was queued first), custom-b still waits for a global slot
+	started["restic-a"].finish <- nil
+	resticRun := runs.expectStarted(t, "restic-b")["restic-b"]
+	runs.expectNoStart(t)
+
+	started["custom-a"].finish <- nil
+	customRun := runs.expectStarted(t, "custom-b")["custom-b"]
+	resticRun.finish <- nil
+	customRun.finish <- errUpload
+	waitForStatus(t, db, resticB, model.StatusSuccess)
+	waitForStatus(t, db, customB, model.StatusFailed)
+}
+
+func TestQueue_LimitedBackendDoesNotBlockOthers(t *testing.T) {
+	db := openTestDB(t)
+	instances := map[model.InstanceID]backend.Backend{
+		"restic-a": newFakeBackend(backend.BackendTypeRestic),
+		"restic-b": newFakeBackend(backend.BackendTypeRestic),
+		"custom":   newFakeBackend(backend.BackendTypeCustomImage),
+	}
+	r, runs := newTestRunner(t, db, instances, testSchedule("restic-a"), testSchedule("restic-b"), testSchedule("custom"))
+	r.SetConcurrencyLimits(0, map[backend.BackendType]int{backend.BackendTypeRestic: 1})
+
+	trigger(t, r, "restic-a")
+	trigger(t, r, "restic-b")
+	trigger(t, r, "custom")
+
+	// The queued restic run does not hold up the custom run behind it
+	runs.expectStarted(t, "restic-a", "custom")
+	runs.expectNoStart(t)
+}
+
+func TestQueue_ResumeQueuedJobsAfterRestart(t *testing.T) {
+	db := openTestDB(t)
+	instances := map[model.InstanceID]backend.Backend{
+		"first":   newFakeBackend(backend.BackendTypeRestic),
+		"second":  newFakeBackend(backend.BackendTypeRestic),
+		"removed": newFakeBackend(backend.BackendTypeRestic),
+	}
+	before, runs := newTestRunner(t, db, instances, testSchedule("first"), testSchedule("second"), testSchedule("removed"))
+	before.SetConcurrencyLimits(1, nil)
+
+	trigger(t, before, "first")
+	runs.expectStarted(t, "first")
+	second := trigger(t, before, "second")
+	removed := trigger(t, before, "removed")
+	waitForStatus(t, db, second, model.StatusQueued)
+	waitForStatus(t, db, removed, model.StatusQueued)
+
+	// A new runner on the same database (Marina restarted with "removed" dropped from the config)
+	after, resumed := newTestRunner(t, db, map[model.InstanceID]backend.Backend{
+		"first":  newFakeBackend(backend.BackendTypeRestic),
+		"second": newFakeBackend(backend.BackendTypeRestic),
+	}, testSchedule("first"), testSchedule("second"))
+	if err := after.ResumeQueuedJobs(context.Background()); err != nil {
+		t.Fatalf("ResumeQueuedJobs() error = %v", err)
+	}
+
+	run := resumed.expectStarted(t, "second")["second"]
+	if run.jobStatusID != second {
+		t.Errorf("resumed job %d, want the queued job %d", run.jobStatusID, second)
+	}
+	status := waitForStatus(t, db, removed, model.StatusAborted)
+	if status.Message != "instance or targets are no longer configured" {
+		t.Errorf("message = %q", status.Message)
+	}
+	resumed.expectNoStart(t)
+}
+
+func TestQueue_StoppedRunStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeouts    model.Timeouts
+		stop        func(r *Runner, jobStatusID int) error
+		wantStatus  model.JobStatusState
+		wantMessage string
+	}{
+		{
+			name: "cancelled on request",
+			stop: func(r *Runner, jobStatusID int) error {
+				return r.CancelJob(context.Background(), jobStatusID)
+			},
+			wantStatus:  model.StatusCancelled,
+			wantMessage: errCancelled.Error(),
+		},
+		{
+			name: "aborted on shutdown",
+			stop: func(r *Runner, jobStatusID int) error {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				r.running[jobStatusID].cancel(errShutdown)
+				return nil
+			},
+			wantStatus:  model.StatusAborted,
+			wantMessage: errShutdown.Error(),
+		},
+		{
+			name: "superseded",
+			stop: func(r *Runner, jobStatusID int) error {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				r.running[jobStatusID].cancel(errSuperseded)
+				return nil
+			},
+			wantStatus:  model.StatusAborted,
+			wantMessage: errSuperseded.Error(),
+		},
+		{
+			name:        "job timeout",
+			timeouts:    model.Timeouts{Job: 50 * time.Millisecond},
+			stop:        func(*Runner, int) error { return nil },
+			wantStatus:  model.StatusFailed,
+			wantMessage: "job timed out after 50ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			schedule := testSchedule("app")
+			schedule.Timeouts = tt.timeouts
+			r, runs := newTestRunner(t, db, map[model.InstanceID]backend.Backend{"app": newFakeBackend(backend.BackendTypeRestic)}, schedule)
+
+			id := trigger(t, r, "app")
+			runs.expectStarted(t, "app")
+			if err := tt.stop(r, id); err != nil {
+				t.Fatalf("stopping the run failed: %v", err)
+			}
+
+			status := waitForStatus(t, db, id, tt.wantStatus)
+			if !strings.Contains(status.Message, tt.wantMessage) {
+				t.Errorf("message = %q, want %q", status.Message, tt.wantMessage)
+			}
+			if status.LastCompletedAt == nil {
+				t.Error("completion time not recorded")
+			}
+		})
+	}
+}
+
+func TestQueue_CancelQueuedJob(t *testing.T) {
+	db := openTestDB(t)
+	instances := map[model.InstanceID]backend.Backend{
+		"first":  newFakeBackend(backend.BackendTypeRestic),
+		"second": newFakeBackend(backend.BackendTypeRestic),
+	}
+	r, runs := newTestRunner(t, db, instances, testSchedule("first"), testSchedule("second"))
+	r.SetConcurrencyLimits(1, nil)
+
+	first := trigger(t, r, "first")
+	runs.expectStarted(t, "first")
+	second := trigger(t, r, "second")
+	waitForStatus(t, db, second, model.StatusQueued)
+
+	if err := r.CancelJob(context.Background(), second); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	waitForStatus(t, db, second, model.StatusCancelled)
+
+	// The cancelled run does not start when the slot frees up
+	if err := r.CancelJob(context.Background(), first); err != nil {
+		t.Fatalf("CancelJob() error = %v", err)
+	}
+	waitForStatus(t, db, first, model.StatusCancelled)
+	runs.expectNoStart(t)
+}
+
+func TestQueue_OverlapPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlap model.OverlapPolicy
+	}{
+		{name: "skip", overlap: model.OverlapSkip},
+		{name: "default is skip"},
+		{name: "queue", overlap: model.OverlapQueue},
+		{name: "cancel previous", overlap: model.OverlapCancelPrevious},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			schedule := testSchedule("app")
+			schedule.Overlap = tt.overlap
+			r, runs := newTestRunner(t, db, map[model.InstanceID]backend.Backend{"app": newFakeBackend(backend.BackendTypeRestic)}, schedule)
+
+			first := trigger(t, r, "app")
+			firstRun := runs.expectStarted(t, "app")["app"]
+			firstIID := waitForStatus(t, db, first, model.StatusInProgress).IID
+			second := trigger(t, r, "app")
+
+			switch tt.overlap {
+			case model.OverlapQueue:
+				waitForStatus(t, db, second, model.StatusQueued)
+				runs.expectNoStart(t)
+				firstRun.finish <- nil
+				waitForStatus(t, db, first, model.StatusSuccess)
+				if run := runs.expectStarted(t, "app")["app"]; run.jobStatusID != second {
+					t.Errorf("started job %d, want %d", run.jobStatusID, second)
+				}
+
+			case model.OverlapCancelPrevious:
+				status := waitForStatus(t, db, first, model.StatusAborted)
+				if status.Message != errSuperseded.Error() {
+					t.Errorf("message of the superseded run = %q, want %q", status.Message, errSuperseded.Error())
+				}
+				if run := runs.expectStarted(t, "app")["app"]; run.jobStatusID != second {
+					t.Errorf("started job %d, want %d", run.jobStatusID, second)
+				}
+
+			default:
+				status := waitForStatus(t, db, second, model.StatusSkipped)
+				if want := fmt.Sprintf("previous run #%d (running) is still active", firstIID); status.Message != want {
+					t.Errorf("message = %q, want %q", status.Message, want)
+				}
+				runs.expectNoStart(t)
+				waitForStatus(t, db, first, model.StatusInProgress)
+			}
+		})
+	}
+}
+
+// TestQueue_CancelPreviousDropsQueuedRuns checks that a superseding run replaces the queued runs
+// of its schedule group, not only the running one
+func TestQueue_CancelPreviousDropsQueuedRuns(t *testing.T) {
+	db := openTestDB(t)
+	instances := map[model.InstanceID]backend.Backend{
+		"other": newFakeBackend(backend.BackendTypeRestic),
+		"app":   newFakeBackend(backend.BackendTypeRestic),
+	}
+	schedule := testSchedule("app")
+	schedule.Overlap = model.OverlapCancelPrevious
+	r, runs := newTestRunner(t, db, instances, testSchedule("other"), schedule)
+	r.SetConcurrencyLimits(1, nil)
+
+	trigger(t, r, "other")
+	otherRun := runs.expectStarted(t, "other")["other"]
+	queued := trigger(t, r, "app")
+	waitForStatus(t, db, queued, model.StatusQueued)
+
+	replacement := trigger(t, r, "app")
+	replacementIID := waitForStatus(t, db, replacement, model.StatusQueued).IID
+	status := waitForStatus(t, db, queued, model.StatusSkipped)
+	if want := fmt.Sprintf("superseded by run #%d", replacementIID); status.Message != want {
+		t.Errorf("message = %q, want %q", status.Message, want)
+	}
+
+	otherRun.finish <- nil
+	if run := runs.expectStarted(t, "app")["app"]; run.jobStatusID != replacement {
+		t.Errorf("started job %d, want %d", run.jobStatusID, replacement)
+	}
+	runs.expectNoStart(t)
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"context"
+	"slices"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// ReplaceBackupInstances switches to the backends of a reloaded config (call SyncBackups afterwards).
+// Backends that are no longer used are closed; if a run of the instance is in progress, it keeps its
+// backend until it finishes. Queued runs of removed instances are marked aborted.
+func (r *Runner) ReplaceBackupInstances(ctx context.Context, instances map[model.InstanceID]backend.Backend) {
+	r.mu.Lock()
+	for id, old := range r.BackupInstances {
+		if instances[id] == old {
+			continue
+		}
+		if r.instanceRunningLocked(id) {
+			r.retired[id] = append(r.retired[id], old)
+			continue
+		}
+		if err := old.Close(); err != nil {
+			r.Logger.Warn("failed to close backend of instance %s: %v", id, err)
+		}
+	}
+	r.BackupInstances = instances
+
+	var removed []int
+	r.queue = slices.DeleteFunc(r.queue, func(job queuedJob) bool {
+		if _, ok := instances[job.schedule.InstanceID]; ok {
+			return false
+		}
+		removed = append(removed, job.jobStatusID)
+		return true
+	})
+	r.dispatchLocked()
+	r.mu.Unlock()
+
+	// Written after releasing r.mu, so dispatching never waits for the database
+	for _, jobStatusID := range removed {
+		if err := r.updateJobStatus(ctx, jobStatusID, func(s *model.JobStatus) {
+			s.Status = model.StatusAborted
+			s.Message = "instance removed from config"
+		}); err != nil {
+			r.Logger.Warn("failed to update job status: %v", err)
+		}
+	}
+}
+
+// closeRetiredLocked closes the backends of an instance that were replaced while it was running. Requires r.mu.
+func (r *Runner) closeRetiredLocked(instanceID model.InstanceID) {
+	for _, old := range r.retired[instanceID] {
+		if err := old.Close(); err != nil {
+			r.Logger.Warn("failed to close backend of instance %s: %v", instanceID, err)
+		}
+	}
+	delete(r.retired, instanceID)
+}
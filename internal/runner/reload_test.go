@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+func TestReplaceBackupInstances_ClosesUnusedBackends(t *testing.T) {
+	kept := newFakeBackend(backend.BackendTypeRestic)
+	replaced := newFakeBackend(backend.BackendTypeRestic)
+	removed := newFakeBackend(backend.BackendTypeRestic)
+	r, _ := newTestRunner(t, openTestDB(t), map[model.InstanceID]backend.Backend{
+		"kept": kept, "replaced": replaced, "removed": removed,
+	}, testSchedule("kept"), testSchedule("replaced"), testSchedule("removed"))
+
+	r.ReplaceBackupInstances(context.Background(), map[model.InstanceID]backend.Backend{
+		"kept":     kept,
+		"replaced": newFakeBackend(backend.BackendTypeRestic),
+	})
+
+	if kept.closed.Load() {
+		t.Error("backend of an unchanged instance was closed")
+	}
+	if !replaced.closed.Load() || !removed.closed.Load() {
+		t.Errorf("unused backends not closed (replaced: %v, removed: %v)", replaced.closed.Load(), removed.closed.Load())
+	}
+}
+
+func TestReplaceBackupInstances_KeepsBackendOfRunningJob(t *testing.T) {
+	old := newFakeBackend(backend.BackendTypeRestic)
+	db := openTestDB(t)
+	r, runs := newTestRunner(t, db, map[model.InstanceID]backend.Backend{"app": old}, testSchedule("app"))
+
+	id := trigger(t, r, "app")
+	run := runs.expectStarted(t, "app")["app"]
+
+	r.ReplaceBackupInstances(context.Background(), map[model.InstanceID]backend.Backend{"app": newFakeBackend(backend.BackendTypeRestic)})
+	if old.closed.Load() {
+		t.Fatal("backend closed while its run is still in progress")
+	}
+
+	run.finish <- nil
+	waitForStatus(t, db, id, model.StatusSuccess)
+	r.runs.Wait()
+	if !old.closed.Load() {
+		t.Error("replaced backend not closed after the run finished")
+	}
+	r.mu.Lock()
+	retired := len(r.retired)
+	r.mu.Unlock()
+	if retired != 0 {
+		t.Errorf("%d instance(s) still have retired backends", retired)
+	}
+}
+
+func TestReplaceBackupInstances_AbortsQueuedRunsOfRemovedInstances(t *testing.T) {
+	db := openTestDB(t)
+	instances := map[model.InstanceID]backend.Backend{
+		"running": newFakeBackend(backend.BackendTypeRestic),
+		"kept":    newFakeBackend(backend.BackendTypeRestic),
+		"removed": newFakeBackend(backend.BackendTypeRestic),
+	}
+	r, runs := newTestRunner(t, db, instances, testSchedule("running"), testSchedule("kept"), testSchedule("removed"))
+	r.SetConcurrencyLimits(1, nil)
+
+	trigger(t, r, "running")
+	run := runs.expectStarted(t, "running")["running"]
+	kept := trigger(t, r, "kept")
+	removed := trigger(t, r, "removed")
+	waitForStatus(t, db, removed, model.StatusQueued)
+
+	r.ReplaceBackupInstances(context.Background(), map[model.InstanceID]backend.Backend{
+		"running": instances["running"],
+		"kept":    instances["kept"],
+	})
+	status := waitForStatus(t, db, removed, model.StatusAborted)
+	if status.Message != "instance removed from config" {
+		t.Errorf("message = %q", status.Message)
+	}
+	waitForStatus(t, db, kept, model.StatusQueued)
+
+	// Only the run of the remaining instance starts once the slot is free
+	run.finish <- nil
+	if started := runs.expectStarted(t, "kept")["kept"]; started.jobStatusID != kept {
+		t.Errorf("started job %d, want %d", started.jobStatusID, kept)
+	}
+	runs.expectNoStart(t)
+}
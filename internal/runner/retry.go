@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/helpers"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// withRetry runs step until it succeeds or the policy's attempts are used up, waiting with
+// exponential backoff between attempts. Every failed attempt is logged.
+// Returns the number of attempts made and the error of the last attempt.
+func withRetry(ctx context.Context, policy model.RetryPolicy, logger *logging.JobLogger, what string, step func() error) (int, error) {
+	maxAttempts := max(policy.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			logger.Info("%s: attempt %d/%d", what, attempt, maxAttempts)
+		}
+		err := step()
+		if err == nil {
+			return attempt, nil
+		}
+		if attempt >= maxAttempts || ctx.Err() != nil {
+			return attempt, err
+		}
+
+		delay := helpers.BackoffDelay(policy.InitialBackoff, policy.Multiplier, attempt)
+		logger.Warn("%s failed (attempt %d/%d), retrying in %s: %v", what, attempt, maxAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(delay):
+		}
+	}
+}
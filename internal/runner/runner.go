@@ -2,10 +2,16 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"reflect"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/client"
@@ -13,6 +19,7 @@ import (
 
 	"github.com/polarfoxDev/marina/internal/backend"
 	"github.com/polarfoxDev/marina/internal/database"
+	"github.com/polarfoxDev/marina/internal/helpers"
 	"github.com/polarfoxDev/marina/internal/logging"
 	"github.com/polarfoxDev/marina/internal/model"
 )
@@ -21,165 +28,230 @@ type cleanupFunc func()
 
 type Runner struct {
 	Cron            *cron.Cron
-	BackupInstances map[model.InstanceID]backend.Backend // keyed by destination ID
+	BackupInstances map[model.InstanceID]backend.Backend // keyed by destination ID; replaced (not modified) on config reload, guarded by mu
 	Docker          *client.Client
 	Logger          *logging.Logger
 	DB              *database.DB // Database for persistent job status tracking
 	HostBackupPath  string       // Actual host path where /backup is mounted from
 
-	// Track scheduled jobs for dynamic updates
-	scheduledJobs map[model.InstanceID]cron.EntryID                 // instance ID -> cron entry ID
-	jobs          map[model.InstanceID]model.InstanceBackupSchedule // instance ID -> backup job config
+	// Track scheduled jobs for dynamic updates (guarded by mu)
+	scheduledJobs map[string]cron.EntryID                 // schedule key (instance@cron) -> cron entry ID
+	jobs          map[string]model.InstanceBackupSchedule // schedule key (instance@cron) -> backup job config
+
+	// Run queue and concurrency limits (see queue.go)
+	mu                sync.Mutex
+	maxJobs           int                         // 0 = unlimited
+	backendLimits     map[backend.BackendType]int // 0 or missing = unlimited
+	queue             []queuedJob
+	running           map[int]*runningJob // job status ID -> running job
+	runningPerBackend map[backend.BackendType]int
+	deferred          map[string]time.Time                   // schedule key -> start of a run deferred by a blackout window
+	chainProgress     map[string]map[model.InstanceID]bool   // chained schedule key -> upstream instances completed since its last run
+	retired           map[model.InstanceID][]backend.Backend // backends replaced by a config reload, closed when the instance's run finishes
+	dryRuns           map[int]bool                           // dry-run commands in progress (see startDryRun)
+	stopping          bool                                   // set by Stop: no new runs are started
+	runs              sync.WaitGroup                         // running jobs (see Stop)
+
+	// runBackup executes a dequeued run (runInstanceBackup; tests replace it to run without Docker)
+	runBackup func(ctx context.Context, job model.InstanceBackupSchedule, jobStatusID int, instanceLogger *logging.JobLogger) error
+	// now is the clock of catch-up checks and blackout windows (time.Now; tests fix it)
+	now func() time.Time
 }
 
 func New(instances map[model.InstanceID]backend.Backend, docker *client.Client, logger *logging.Logger, db *database.DB, hostBackupPath string) *Runner {
-	return &Runner{
-		Cron:            cron.New(cron.WithParser(cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow))),
-		BackupInstances: instances,
-		Docker:          docker,
-		Logger:          logger,
-		DB:              db,
-		HostBackupPath:  hostBackupPath,
-		scheduledJobs:   make(map[model.InstanceID]cron.EntryID),
-		jobs:            make(map[model.InstanceID]model.InstanceBackupSchedule),
+	r := &Runner{
+		Cron:              cron.New(cron.WithParser(helpers.CronParser)),
+		BackupInstances:   instances,
+		Docker:            docker,
+		Logger:            logger,
+		DB:                db,
+		HostBackupPath:    hostBackupPath,
+		scheduledJobs:     make(map[string]cron.EntryID),
+		jobs:              make(map[string]model.InstanceBackupSchedule),
+		backendLimits:     make(map[backend.BackendType]int),
+		running:           make(map[int]*runningJob),
+		runningPerBackend: make(map[backend.BackendType]int),
+		deferred:          make(map[string]time.Time),
+		chainProgress:     make(map[string]map[model.InstanceID]bool),
+		retired:           make(map[model.InstanceID][]backend.Backend),
+		dryRuns:           make(map[int]bool),
 	}
+	r.runBackup = r.runInstanceBackup
+	r.now = time.Now
+	return r
 }
 
+// ScheduleBackup registers the cron entry of a schedule group (replacing an existing entry of the same group)
 func (r *Runner) ScheduleBackup(backupSchedule model.InstanceBackupSchedule) error {
-	// Check if already scheduled
-	if existingEntry, ok := r.scheduledJobs[backupSchedule.InstanceID]; ok {
-		// Check if schedule or config changed
-		if existing, found := r.jobs[backupSchedule.InstanceID]; found {
-			if existing.ScheduleCron == backupSchedule.ScheduleCron && jobsEqual(existing, backupSchedule) {
-				// No changes, skip
-				return nil
-			}
-		}
-		// Remove old entry
-		r.Cron.Remove(existingEntry)
-		delete(r.scheduledJobs, backupSchedule.InstanceID)
+	r.mu.Lock()
+	changed, err := r.scheduleLocked(backupSchedule)
+	r.mu.Unlock()
+	if changed {
+		r.updateNextRunTime(backupSchedule.InstanceID)
 	}
+	return err
+}
 
-	// Schedule new job
-	entryID, err := r.Cron.AddFunc(backupSchedule.ScheduleCron, func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 12*time.Hour)
-		defer cancel()
-
-		// Create job status first to get IDs for logger
-		var jobStatusID, jobStatusIID int
-		if r.DB != nil {
-			jobStatus, err := r.DB.ScheduleNewJob(ctx, string(backupSchedule.InstanceID))
-			if err != nil {
-				r.Logger.Error("failed to create job status: %v", err)
-				return
-			}
-			jobStatusID = jobStatus.ID
-			jobStatusIID = jobStatus.IID
-		}
+// scheduleLocked registers the cron entry of a schedule group and reports whether anything changed. Requires r.mu.
+func (r *Runner) scheduleLocked(backupSchedule model.InstanceBackupSchedule) (bool, error) {
+	key := backupSchedule.Key()
 
-		// Create instance-level logger with job status IDs
-		instanceLogger := r.Logger.NewJobLogger(string(backupSchedule.InstanceID), jobStatusID, jobStatusIID)
+	// Check if already scheduled
+	if existing, found := r.jobs[key]; found && jobsEqual(existing, backupSchedule) {
+		// No changes, skip
+		return false, nil
+	}
+	// Remove old entry
+	r.unscheduleLocked(key)
 
-		instanceLogger.Info("instance backup started (%d targets)", len(backupSchedule.Targets))
-		startTime := time.Now()
+	// Chained schedules have no cron entry; they are started when their upstream instances complete
+	if len(backupSchedule.After) > 0 {
+		r.jobs[key] = backupSchedule
+		return true, nil
+	}
 
-		if err := r.runInstanceBackup(ctx, backupSchedule, jobStatusID, instanceLogger); err != nil {
-			instanceLogger.Error("instance backup failed: %v", err)
-		} else {
-			duration := time.Since(startTime)
-			instanceLogger.Info("instance backup completed (duration: %v)", duration)
+	// Schedule new job
+	entryID, err := r.Cron.AddFunc(helpers.CronSpec(backupSchedule.ScheduleCron, backupSchedule.Timezone), func() {
+		if backupSchedule.Jitter > 0 {
+			// Spread runs of nodes and instances sharing a schedule
+			delay := rand.N(backupSchedule.Jitter)
+			r.Logger.Debug("delaying run of instance %s by %v (jitter)", backupSchedule.InstanceID, delay.Round(time.Second))
+			time.Sleep(delay)
 		}
+		r.startScheduled(backupSchedule, "scheduled run")
 	})
 	if err != nil {
-		return err
+		return true, err
 	}
 
-	r.scheduledJobs[backupSchedule.InstanceID] = entryID
-	r.jobs[backupSchedule.InstanceID] = backupSchedule
+	r.scheduledJobs[key] = entryID
+	r.jobs[key] = backupSchedule
+	return true, nil
+}
 
-	nextRunTime := r.getNextRunTime(backupSchedule.InstanceID)
-	// Update next run time in DB
-	if r.DB != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := r.DB.UpdateNextRunTime(ctx, string(backupSchedule.InstanceID), nextRunTime); err != nil {
-			r.Logger.Warn("failed to update next run time for instance %s: %v", backupSchedule.InstanceID, err)
+// RemoveJob removes all scheduled backup jobs (schedule groups) of an instance
+func (r *Runner) RemoveJob(instanceID model.InstanceID) {
+	removed := false
+	r.mu.Lock()
+	for key, job := range r.jobs {
+		if job.InstanceID == instanceID {
+			r.unscheduleLocked(key)
+			removed = true
 		}
 	}
+	r.mu.Unlock()
+	if !removed || r.DB == nil {
+		return
+	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.DB.ArchiveInstance(ctx, string(instanceID)); err != nil {
+		r.Logger.Warn("failed to archive job status for instance %s: %v", instanceID, err)
+	}
 }
 
-// RemoveJob removes a scheduled backup job for an instance
-func (r *Runner) RemoveJob(instanceID model.InstanceID) {
-	if entryID, ok := r.scheduledJobs[instanceID]; ok {
+// unscheduleLocked removes the cron entry of a schedule group. Requires r.mu.
+func (r *Runner) unscheduleLocked(key string) {
+	if entryID, ok := r.scheduledJobs[key]; ok {
 		r.Cron.Remove(entryID)
-		delete(r.scheduledJobs, instanceID)
-
-		if r.DB != nil {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if err := r.DB.ArchiveInstance(ctx, string(instanceID)); err != nil {
-				r.Logger.Warn("failed to archive job status for instance %s: %v", instanceID, err)
-			}
-		}
-
-		delete(r.jobs, instanceID)
+		delete(r.scheduledJobs, key)
 	}
+	delete(r.jobs, key)
+	delete(r.chainProgress, key)
 }
 
 // SyncBackups updates the scheduler with a new set of discovered backups
 // Adds new backups, removes deleted ones, and updates changed ones
 func (r *Runner) SyncBackups(newBackups []model.InstanceBackupSchedule) {
 	r.Logger.Info("syncing %d discovered instance backups...", len(newBackups))
-	newSet := make(map[model.InstanceID]model.InstanceBackupSchedule)
+	newSet := make(map[string]model.InstanceBackupSchedule)
+	instances := make(map[model.InstanceID]bool)
 	for _, j := range newBackups {
-		newSet[j.InstanceID] = j
+		newSet[j.Key()] = j
+		instances[j.InstanceID] = true
 	}
 
 	if r.DB != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		if err := r.DB.AddOrUpdateSchedules(ctx, newSet); err != nil {
+		if err := r.DB.AddOrUpdateSchedules(ctx, mergeSchedules(newBackups)); err != nil {
 			r.Logger.Warn("failed to update schedules: %v", err)
 		}
 	}
 
+	// Work on a copy: the maps are shared with cron callbacks and the run queue (see r.mu)
+	r.mu.Lock()
+	current := maps.Clone(r.jobs)
+	backends := r.BackupInstances
+	r.mu.Unlock()
+
 	// Remove jobs that no longer exist
-	for id := range r.jobs {
-		if _, exists := newSet[id]; !exists {
-			r.Logger.Info("removing instance job %s (no longer exists)", id)
-			r.RemoveJob(id)
+	for key, job := range current {
+		if _, exists := newSet[key]; exists {
+			continue
+		}
+		if instances[job.InstanceID] {
+			r.Logger.Info("removing schedule %s of instance %s (no longer exists)", job.Describe(), job.InstanceID)
+			r.mu.Lock()
+			r.unscheduleLocked(key)
+			r.mu.Unlock()
+			r.updateNextRunTime(job.InstanceID)
+		} else {
+			r.Logger.Info("removing instance job %s (no longer exists)", job.InstanceID)
+			r.RemoveJob(job.InstanceID)
 		}
 	}
 
 	// Add or update jobs
-	for id, job := range newSet {
+	for key, job := range newSet {
 		// Validate instance exists
-		if _, ok := r.BackupInstances[job.InstanceID]; !ok {
-			r.Logger.Warn("instance job %s references unknown instance, skipping", id)
+		if _, ok := backends[job.InstanceID]; !ok {
+			r.Logger.Warn("instance job %s references unknown instance, skipping", job.InstanceID)
 			continue
 		}
 
 		// Check if it's new or changed BEFORE scheduling
-		existing, found := r.jobs[id]
+		existing, found := current[key]
 		isNew := !found
 		isChanged := found && !jobsEqual(existing, job)
 
 		if err := r.ScheduleBackup(job); err != nil {
-			r.Logger.Error("schedule instance %s: %v", id, err)
+			r.Logger.Error("schedule instance %s: %v", job.InstanceID, err)
 		} else {
 			// Only log if it's new or changed
 			if isNew || isChanged {
-				r.Logger.Info("scheduled instance %s (%d targets, schedule: %s)", id, len(job.Targets), job.ScheduleCron)
+				r.Logger.Info("scheduled instance %s (%d targets, schedule: %s)", job.InstanceID, len(job.Targets), job.Describe())
 			}
 		}
 	}
 }
 
+// mergeSchedules combines the schedule groups of each instance into one schedule for display
+// (backup_schedules): all targets, and the distinct cron schedules joined with "; "
+func mergeSchedules(schedules []model.InstanceBackupSchedule) map[model.InstanceID]model.InstanceBackupSchedule {
+	merged := make(map[model.InstanceID]model.InstanceBackupSchedule)
+	for _, schedule := range schedules {
+		existing, ok := merged[schedule.InstanceID]
+		if !ok {
+			schedule.ScheduleCron = schedule.Describe()
+			schedule.Targets = slices.Clone(schedule.Targets)
+			merged[schedule.InstanceID] = schedule
+			continue
+		}
+		existing.ScheduleCron += "; " + schedule.ScheduleCron
+		existing.Targets = append(existing.Targets, schedule.Targets...)
+		merged[schedule.InstanceID] = existing
+	}
+	return merged
+}
+
 // jobsEqual checks if two instance backup jobs are functionally equivalent
 func jobsEqual(a, b model.InstanceBackupSchedule) bool {
-	if a.ScheduleCron != b.ScheduleCron || len(a.Targets) != len(b.Targets) {
+	if a.ScheduleCron != b.ScheduleCron || a.Timezone != b.Timezone || a.Jitter != b.Jitter || a.Overlap != b.Overlap || len(a.Targets) != len(b.Targets) {
+		return false
+	}
+	if !reflect.DeepEqual(a.Blackouts, b.Blackouts) || !slices.Equal(a.After, b.After) {
 		return false
 	}
 
@@ -196,34 +268,65 @@ func jobsEqual(a, b model.InstanceBackupSchedule) bool {
 
 	return true
 }
-func (r *Runner) Start()                   { r.Cron.Start() }
-func (r *Runner) Stop(ctx context.Context) { r.Cron.Stop() }
+func (r *Runner) Start() { r.Cron.Start() }
 
-func (r *Runner) TriggerNow(ctx context.Context, job model.InstanceBackupSchedule) error {
-	// Get or create job status to get IDs for logger
-	var jobStatusID, jobStatusIID int
-	if r.DB != nil {
-		jobStatus, err := r.DB.ScheduleNewJob(ctx, string(job.InstanceID))
-		if err != nil {
-			return fmt.Errorf("failed to create job status: %w", err)
-		}
-		jobStatusID = jobStatus.ID
-		jobStatusIID = jobStatus.IID
-	}
-
-	instanceLogger := r.Logger.NewJobLogger(string(job.InstanceID), jobStatusID, jobStatusIID)
-	return r.runInstanceBackup(ctx, job, jobStatusID, instanceLogger)
+// TriggerNow queues an immediate run of an instance and returns its job ID; it starts as soon as the concurrency limits allow
+func (r *Runner) TriggerNow(ctx context.Context, job model.InstanceBackupSchedule) (int, error) {
+	return r.enqueue(ctx, job, "manual run")
 }
 
-// getNextRunTime retrieves the next scheduled run time for an instance from the cron entry
+// getNextRunTime returns the earliest next run of any schedule group of an instance,
+// taking blackout windows and runs deferred by them into account
 func (r *Runner) getNextRunTime(instanceID model.InstanceID) *time.Time {
-	if entryID, ok := r.scheduledJobs[instanceID]; ok {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next *time.Time
+	for key, entryID := range r.scheduledJobs {
+		job := r.jobs[key]
+		if job.InstanceID != instanceID {
+			continue
+		}
 		entry := r.Cron.Entry(entryID)
-		if !entry.Next.IsZero() {
-			return &entry.Next
+		if entry.Schedule == nil {
+			continue
+		}
+		runAt := helpers.NextAllowedRun(entry.Schedule, job.Blackouts, entry.Next)
+		if !runAt.IsZero() && (next == nil || runAt.Before(*next)) {
+			next = &runAt
 		}
 	}
-	return nil
+
+	for key, runAt := range r.deferred {
+		if r.jobs[key].InstanceID == instanceID && (next == nil || runAt.Before(*next)) {
+			next = &runAt
+		}
+	}
+	return next
+}
+
+// saveJobTarget stores the result of a target (best effort - also after the run was cancelled)
+func (r *Runner) saveJobTarget(ctx context.Context, result *model.JobTarget, logger *logging.JobLogger) {
+	if r.DB == nil || result.JobStatusID == 0 {
+		return
+	}
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := r.DB.SaveJobTarget(dbCtx, *result); err != nil {
+		logger.Warn("failed to record result of target %s: %v", result.TargetID, err)
+	}
+}
+
+// updateNextRunTime stores the next scheduled run of an instance (best effort)
+func (r *Runner) updateNextRunTime(instanceID model.InstanceID) {
+	if r.DB == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.DB.UpdateNextRunTime(ctx, string(instanceID), r.getNextRunTime(instanceID)); err != nil {
+		r.Logger.Warn("failed to update next run time for instance %s: %v", instanceID, err)
+	}
 }
 
 // updateJobStatus is a helper to update job status in the database
@@ -251,20 +354,15 @@ func (r *Runner) updateJobStatus(ctx context.Context, jobStatusID int, updateFn
 
 // runInstanceBackup executes all backups for an instance in a single Restic operation
 func (r *Runner) runInstanceBackup(ctx context.Context, job model.InstanceBackupSchedule, jobStatusID int, instanceLogger *logging.JobLogger) error {
+	r.mu.Lock()
 	dest, ok := r.BackupInstances[job.InstanceID]
+	r.mu.Unlock()
 	if !ok {
 		return fmt.Errorf("instance %q not found", job.InstanceID)
 	}
 
-	nextRunTime := r.getNextRunTime(job.InstanceID)
 	// Update next run time in DB (best effort - don't block backup on DB issues)
-	if r.DB != nil {
-		dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := r.DB.UpdateNextRunTime(dbCtx, string(job.InstanceID), nextRunTime); err != nil {
-			r.Logger.Warn("failed to update next run time for instance %s: %v", job.InstanceID, err)
-		}
-	}
+	r.updateNextRunTime(job.InstanceID)
 
 	startTime := time.Now()
 
@@ -273,10 +371,32 @@ func (r *Runner) runInstanceBackup(ctx context.Context, job model.InstanceBackup
 		status.Status = model.StatusInProgress
 		status.LastStartedAt = &startTime
 		status.LastTargetsTotal = len(job.Targets)
+		status.Attempts = 1
 	}); err != nil {
 		return err
 	}
 
+	// Start and end of each phase are stored in job_phases
+	phases := r.newPhaseTimeline(jobStatusID, instanceLogger)
+
+	// Make sure the staging mount can hold this run before copying anything
+	endPreflight := phases.start(ctx, "", model.PhasePreflight)
+	stagingEstimates, err := r.preflightStaging(ctx, job, instanceLogger)
+	endPreflight(err)
+	if err != nil {
+		instanceLogger.Error("preflight failed: %v", err)
+		if updateErr := r.updateJobStatus(ctx, jobStatusID, func(status *model.JobStatus) {
+			status.Status = model.StatusFailed
+			now := time.Now()
+			status.LastCompletedAt = &now
+			status.LastTargetsSuccessful = 0
+			status.Message = fmt.Sprintf("preflight failed: %v", err)
+		}); updateErr != nil {
+			r.Logger.Warn("failed to update job status: %v", updateErr)
+		}
+		return fmt.Errorf("preflight failed: %w", err)
+	}
+
 	// Use instance start time as timestamp for all staged paths
 	timestamp := startTime.Format("20060102-150405")
 
@@ -285,53 +405,124 @@ func (r *Runner) runInstanceBackup(ctx context.Context, job model.InstanceBackup
 
 	var allPaths []string
 	var allTags []string
+	var directVolumes []backend.DirectVolume // volumes read in place by the backend (staging: direct)
+	var streamTargets []model.BackupTarget   // databases piped into the backend after staging (staging: stream)
+	attempts := 1                            // highest attempt number of any retried step
 
 	// Track cleanup functions to defer
 	var cleanups []cleanupFunc
 	defer func() {
+		endCleanup := phases.start(ctx, "", model.PhaseCleanup)
 		// Run target-specific cleanups first (container restarts, temp file removal)
 		for _, cleanup := range cleanups {
 			cleanup()
 		}
 		// Then remove the entire instance timestamp staging directory
-		if err := os.RemoveAll(instanceStagingDir); err != nil {
+		err := os.RemoveAll(instanceStagingDir)
+		if err != nil {
 			instanceLogger.Warn("failed to remove staging directory %s: %v", instanceStagingDir, err)
 		} else {
 			instanceLogger.Debug("removed staging directory: %s", instanceStagingDir)
 		}
+		endCleanup(err)
 	}()
 
 	// Track failed targets
 	var failedTargets []string
+	// Steps that exceeded their timeout are named in the job status message
+	var timedOut []string
+	noteTimeout := func(err error) {
+		var timeoutErr *timeoutError
+		if errors.As(err, &timeoutErr) {
+			timedOut = append(timedOut, timeoutErr.Error())
+		}
+	}
+
+	// Per-target results, stored in job_targets whenever they change. Staged targets stay
+	// in progress until the upload; whatever is still in progress when the run ends was never uploaded.
+	var staged []*model.JobTarget
+	saveResult := func(result *model.JobTarget, status model.JobStatusState, err error) {
+		result.Status = status
+		if err != nil {
+			result.Message = err.Error()
+		}
+		r.saveJobTarget(ctx, result, instanceLogger)
+	}
+	defer func() {
+		for _, result := range staged {
+			if result.Status == model.StatusInProgress {
+				saveResult(result, model.StatusAborted, errors.New("run ended before the upload"))
+			}
+		}
+	}()
 
 	// Process each target and collect staged paths
 	for _, target := range job.Targets {
 		// Create target-specific logger for detailed logs
 		targetLogger := instanceLogger.WithTarget(target.ID)
 		targetLogger.Info("staging %s: %s", target.Type, target.Name)
+		result := &model.JobTarget{JobStatusID: jobStatusID, TargetID: target.ID, Type: target.Type, DBKind: target.DBKind}
+		stagingStart := time.Now()
 
 		switch target.Type {
 		case model.TargetVolume:
-			paths, cleanup, err := r.stageVolume(ctx, string(job.InstanceID), timestamp, target, targetLogger)
+			var paths []string
+			var cleanup cleanupFunc
+			n, err := withRetry(ctx, job.Retry, targetLogger, "volume staging", func() error {
+				var err error
+				paths, cleanup, err = r.stageVolume(ctx, string(job.InstanceID), timestamp, job.Helper, job.Timeouts, phases, target, targetLogger)
+				return err
+			})
+			attempts = max(attempts, n)
+			result.StagingDurationMs = time.Since(stagingStart).Milliseconds()
 			if err != nil {
 				targetLogger.Warn("failed to stage volume: %v", err)
+				noteTimeout(err)
+				saveResult(result, model.StatusFailed, err)
 				failedTargets = append(failedTargets, fmt.Sprintf("volume:%s", target.Name))
 				continue // Skip this target but continue with others
 			}
 			targetLogger.Info("volume staged successfully (%d paths)", len(paths))
+			if usesStagingSpace(target) {
+				result.StagedBytes = r.recordStagingSize(ctx, job.InstanceID, jobStatusID, target.ID, stagingEstimates[target.ID], paths, targetLogger)
+			}
+			saveResult(result, model.StatusInProgress, nil)
+			staged = append(staged, result)
 			allPaths = append(allPaths, paths...)
+			if target.Staging == model.StagingDirect {
+				directVolumes = append(directVolumes, backend.DirectVolume{Name: target.Name, MountPath: directVolumeMountPath(target.Name)})
+			}
 			if cleanup != nil {
 				cleanups = append(cleanups, cleanup)
 			}
 
 		case model.TargetDB:
-			path, cleanup, err := r.stageDatabase(ctx, string(job.InstanceID), timestamp, target, targetLogger)
+			if target.Staging == model.StagingStream {
+				// Nothing to stage; the dump is streamed after the staged paths are backed up
+				targetLogger.Info("database dump will be streamed")
+				streamTargets = append(streamTargets, target)
+				continue
+			}
+			var path string
+			var cleanup cleanupFunc
+			n, err := withRetry(ctx, job.Retry, targetLogger, "database dump", func() error {
+				var err error
+				path, cleanup, err = r.stageDatabase(ctx, string(job.InstanceID), timestamp, job.Timeouts, phases, target, result, targetLogger)
+				return err
+			})
+			attempts = max(attempts, n)
+			result.StagingDurationMs = time.Since(stagingStart).Milliseconds()
 			if err != nil {
 				targetLogger.Warn("failed to stage database: %v", err)
+				noteTimeout(err)
+				saveResult(result, model.StatusFailed, err)
 				failedTargets = append(failedTargets, fmt.Sprintf("db:%s", target.Name))
 				continue // Skip this target but continue with others
 			}
 			targetLogger.Info("database dump completed successfully")
+			result.StagedBytes = r.recordStagingSize(ctx, job.InstanceID, jobStatusID, target.ID, stagingEstimates[target.ID], []string{path}, targetLogger)
+			saveResult(result, model.StatusInProgress, nil)
+			staged = append(staged, result)
 			allPaths = append(allPaths, path)
 			if cleanup != nil {
 				cleanups = append(cleanups, cleanup)
@@ -339,6 +530,7 @@ func (r *Runner) runInstanceBackup(ctx context.Context, job model.InstanceBackup
 
 		default:
 			targetLogger.Warn("unknown target type: %s", target.Type)
+			saveResult(result, model.StatusFailed, fmt.Errorf("unknown target type: %s", target.Type))
 			failedTargets = append(failedTargets, fmt.Sprintf("%s:%s", target.Type, target.Name))
 			continue
 		}
@@ -347,12 +539,16 @@ func (r *Runner) runInstanceBackup(ctx context.Context, job model.InstanceBackup
 		allTags = append(allTags, fmt.Sprintf("%s:%s", target.Type, target.Name))
 	}
 	// Check if all targets failed
-	if len(allPaths) == 0 {
+	if len(allPaths) == 0 && len(streamTargets) == 0 {
 		if err := r.updateJobStatus(ctx, jobStatusID, func(status *model.JobStatus) {
 			status.Status = model.StatusFailed
 			now := time.Now()
 			status.LastCompletedAt = &now
 			status.LastTargetsSuccessful = 0
+			status.Attempts = attempts
+			if len(timedOut) > 0 {
+				status.Message = strings.Join(timedOut, "; ")
+			}
 		}); err != nil {
 			r.Logger.Warn("failed to update job status: %v", err)
 		}
@@ -380,34 +576,117 @@ func (r *Runner) runInstanceBackup(ctx context.Context, job model.InstanceBackup
 
 	allTags = deduplicate(allTags)
 
-	// Perform single backup with all collected paths
-	instanceLogger.Info("backing up %d paths to instance %s using backend %s: %s", len(allPaths), job.InstanceID, dest.GetType(), allPaths)
-	instanceLogger.Debug("backend timeout: %s", dest.GetResticTimeout())
+	// Store a checksum manifest of the staged data in the same snapshot
+	if manifestPath, ok := writeManifest(instanceStagingDir, allPaths, instanceLogger); ok {
+		allPaths = append(allPaths, manifestPath)
+	}
+
+	if len(allPaths) > 0 {
+		// Perform single backup with all collected paths
+		instanceLogger.Info("backing up %d paths to instance %s using backend %s: %s", len(allPaths), job.InstanceID, dest.GetType(), allPaths)
+		instanceLogger.Debug("backend timeout: %s", dest.GetResticTimeout())
 
-	if dest.GetType() == backend.BackendTypeCustomImage {
-		instanceLogger.Info("using custom image %s", dest.GetImage())
-		// Type assert to CustomImageBackend to set the logger
-		if customBackend, ok := dest.(*backend.CustomImageBackend); ok {
-			customBackend.SetLogger(instanceLogger)
+		if dest.GetType() == backend.BackendTypeCustomImage {
+			instanceLogger.Info("using custom image %s", dest.GetImage())
+			// Type assert to CustomImageBackend to set the logger
+			if customBackend, ok := dest.(*backend.CustomImageBackend); ok {
+				customBackend.SetLogger(instanceLogger)
+			}
+		}
+
+		n, err := withRetry(ctx, job.Retry, instanceLogger, "upload", func() (err error) {
+			endUpload := phases.start(ctx, "", model.PhaseUpload)
+			defer func() { endUpload(err) }()
+			var logs string
+			if len(directVolumes) > 0 {
+				// Direct volumes are mounted into a restic helper container instead of being staged
+				resticBackend, ok := dest.(*backend.ResticBackend)
+				if !ok {
+					return fmt.Errorf("staging: direct requires a restic repository")
+				}
+				instanceLogger.Info("reading %d volume(s) in place", len(directVolumes))
+				logs, err = resticBackend.BackupDirect(ctx, r.Docker, directVolumes, allPaths, allTags)
+			} else {
+				logs, err = dest.Backup(ctx, allPaths, allTags)
+			}
+			instanceLogger.Debug("%s", logs)
+			return err
+		})
+		attempts = max(attempts, n)
+		uploadErr := fmt.Errorf("upload failed: %w", explainTimeout(ctx, err))
+		for _, result := range staged {
+			if err != nil {
+				saveResult(result, model.StatusFailed, uploadErr)
+			} else {
+				saveResult(result, model.StatusSuccess, nil)
+			}
+		}
+		if err != nil {
+			// Streamed dumps are not attempted after a failed upload; record them so every target has a result
+			for _, target := range streamTargets {
+				result := &model.JobTarget{JobStatusID: jobStatusID, TargetID: target.ID, Type: target.Type, DBKind: target.DBKind}
+				saveResult(result, model.StatusFailed, fmt.Errorf("not streamed: %w", uploadErr))
+			}
+			if updateErr := r.updateJobStatus(ctx, jobStatusID, func(status *model.JobStatus) {
+				status.Status = model.StatusFailed
+				now := time.Now()
+				status.LastCompletedAt = &now
+				status.LastTargetsSuccessful = 0
+				status.Attempts = attempts
+				status.Message = strings.Join(append(slices.Clone(timedOut), uploadErr.Error()), "; ")
+			}); updateErr != nil {
+				r.Logger.Warn("failed to update job status: %v", updateErr)
+			}
+			return fmt.Errorf("backup failed: %w", err)
 		}
 	}
 
-	logs, err := dest.Backup(ctx, allPaths, allTags)
-	instanceLogger.Debug("%s", logs)
-	if err != nil {
-		if updateErr := r.updateJobStatus(ctx, jobStatusID, func(status *model.JobStatus) {
+	// Stream database dumps that skip staging, each into a snapshot of its own
+	for _, target := range streamTargets {
+		targetLogger := instanceLogger.WithTarget(target.ID)
+		result := &model.JobTarget{JobStatusID: jobStatusID, TargetID: target.ID, Type: target.Type, DBKind: target.DBKind}
+		streamStart := time.Now()
+		var err error
+		if resticBackend, ok := dest.(*backend.ResticBackend); ok {
+			var n int
+			n, err = withRetry(ctx, job.Retry, targetLogger, "database stream", func() error {
+				return r.streamDatabase(ctx, resticBackend, job.Timeouts, phases, target, result, targetLogger)
+			})
+			attempts = max(attempts, n)
+		} else {
+			err = fmt.Errorf("staging: stream requires a restic repository")
+		}
+		result.StagingDurationMs = time.Since(streamStart).Milliseconds()
+		if err != nil {
+			targetLogger.Warn("failed to stream database: %v", err)
+			noteTimeout(err)
+			saveResult(result, model.StatusFailed, err)
+			failedTargets = append(failedTargets, fmt.Sprintf("db:%s", target.Name))
+			continue
+		}
+		targetLogger.Info("database dump streamed successfully")
+		saveResult(result, model.StatusSuccess, nil)
+	}
+	if len(failedTargets) == len(job.Targets) {
+		if err := r.updateJobStatus(ctx, jobStatusID, func(status *model.JobStatus) {
 			status.Status = model.StatusFailed
 			now := time.Now()
 			status.LastCompletedAt = &now
 			status.LastTargetsSuccessful = 0
-		}); updateErr != nil {
-			r.Logger.Warn("failed to update job status: %v", updateErr)
+			status.Attempts = attempts
+			if len(timedOut) > 0 {
+				status.Message = strings.Join(timedOut, "; ")
+			}
+		}); err != nil {
+			r.Logger.Warn("failed to update job status: %v", err)
 		}
-		return fmt.Errorf("backup failed: %w", err)
+		return fmt.Errorf("all targets failed: %v", failedTargets)
 	}
 
 	// Apply retention policy
-	_, _ = dest.DeleteOldSnapshots(ctx, job.Retention.KeepDaily, job.Retention.KeepWeekly, job.Retention.KeepMonthly)
+	endRetention := phases.start(ctx, "", model.PhaseRetention)
+	_, err = dest.DeleteOldSnapshots(ctx, job.Retention.KeepDaily, job.Retention.KeepWeekly, job.Retention.KeepMonthly)
+	endRetention(err)
 
 	// Update job status to success/partial success
 	if err := r.updateJobStatus(ctx, jobStatusID, func(status *model.JobStatus) {
@@ -418,6 +697,10 @@ func (r *Runner) runInstanceBackup(ctx context.Context, job model.InstanceBackup
 		now := time.Now()
 		status.LastCompletedAt = &now
 		status.LastTargetsSuccessful = len(job.Targets) - len(failedTargets)
+		status.Attempts = attempts
+		if len(timedOut) > 0 {
+			status.Message = strings.Join(timedOut, "; ")
+		}
 	}); err != nil {
 		r.Logger.Warn("failed to update job status: %v", err)
 	}
@@ -0,0 +1,182 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/database"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// fakeBackend only provides the backend type of a test instance; the runs themselves are faked by fakeRuns
+type fakeBackend struct {
+	backendType backend.BackendType
+	closed      atomic.Bool
+}
+
+func newFakeBackend(backendType backend.BackendType) *fakeBackend {
+	return &fakeBackend{backendType: backendType}
+}
+
+func (b *fakeBackend) Init(context.Context) error { return nil }
+func (b *fakeBackend) Backup(context.Context, []string, []string) (string, error) {
+	return "", nil
+}
+func (b *fakeBackend) DeleteOldSnapshots(context.Context, int, int, int) (string, error) {
+	return "", nil
+}
+func (b *fakeBackend) Close() error                 { b.closed.Store(true); return nil }
+func (b *fakeBackend) GetType() backend.BackendType { return b.backendType }
+func (b *fakeBackend) GetImage() string             { return "" }
+func (b *fakeBackend) GetResticTimeout() string     { return "" }
+
+// fakeRun is a run started by the runner; it lasts until the test finishes it or its context ends
+type fakeRun struct {
+	instanceID  model.InstanceID
+	jobStatusID int
+	finish      chan error
+}
+
+// fakeRuns replaces runInstanceBackup and reports every started run on started
+type fakeRuns struct {
+	started chan *fakeRun
+}
+
+func (f *fakeRuns) run(r *Runner) func(context.Context, model.InstanceBackupSchedule, int, *logging.JobLogger) error {
+	return func(ctx context.Context, job model.InstanceBackupSchedule, jobStatusID int, _ *logging.JobLogger) error {
+		run := &fakeRun{instanceID: job.InstanceID, jobStatusID: jobStatusID, finish: make(chan error, 1)}
+		if err := r.updateJobStatus(ctx, jobStatusID, func(s *model.JobStatus) { s.Status = model.StatusInProgress }); err != nil {
+			return err
+		}
+		f.started <- run
+		select {
+		case err := <-run.finish:
+			// Record the outcome like runInstanceBackup does
+			status := model.StatusSuccess
+			if err != nil {
+				status = model.StatusFailed
+			}
+			if updateErr := r.updateJobStatus(ctx, jobStatusID, func(s *model.JobStatus) { s.Status = status }); updateErr != nil {
+				return updateErr
+			}
+			return err
+		case <-ctx.Done():
+			// The status updates of a cancelled run fail, execute records why it stopped
+			return ctx.Err()
+		}
+	}
+}
+
+// expectStarted waits for the next started runs, checks their instances (in any order) and returns
+// them by instance
+func (f *fakeRuns) expectStarted(t *testing.T, instances ...model.InstanceID) map[model.InstanceID]*fakeRun {
+	t.Helper()
+	runs := make(map[model.InstanceID]*fakeRun)
+	for range instances {
+		select {
+		case run := <-f.started:
+			runs[run.instanceID] = run
+		case <-time.After(5 * time.Second):
+			t.Fatalf("started %v, want %v", slices.Sorted(maps.Keys(runs)), instances)
+		}
+	}
+	if got := slices.Sorted(maps.Keys(runs)); !slices.Equal(got, slices.Sorted(slices.Values(instances))) {
+		t.Fatalf("started %v, want %v", got, instances)
+	}
+	return runs
+}
+
+// expectNoStart checks that no run starts within a short time
+func (f *fakeRuns) expectNoStart(t *testing.T) {
+	t.Helper()
+	select {
+	case run := <-f.started:
+		t.Fatalf("unexpected run of instance %s started", run.instanceID)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// openTestDB creates a status database in a temp directory
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "marina.db"))
+	if err != nil {
+		t.Fatalf("failed to initialize test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestRunner creates a runner on db with the given schedule groups whose runs are faked.
+// Runs still active when the test ends are aborted.
+func newTestRunner(t *testing.T, db *database.DB, instances map[model.InstanceID]backend.Backend, schedules ...model.InstanceBackupSchedule) (*Runner, *fakeRuns) {
+	t.Helper()
+	logger, err := logging.New(db.GetDB(), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(instances, nil, logger, db, "")
+	runs := &fakeRuns{started: make(chan *fakeRun, 16)}
+	r.runBackup = runs.run(r)
+	r.SyncBackups(schedules)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r.Stop(ctx)
+	})
+	return r, runs
+}
+
+// testSchedule returns a daily schedule group of an instance with a single volume target
+func testSchedule(instanceID model.InstanceID) model.InstanceBackupSchedule {
+	return model.InstanceBackupSchedule{
+		InstanceID:   instanceID,
+		ScheduleCron: "0 3 * * *",
+		Targets:      []model.BackupTarget{{ID: "volume:" + string(instanceID), Name: string(instanceID), Type: model.TargetVolume}},
+	}
+}
+
+// trigger requests a manual run of the instance's schedule group and returns its job status ID
+func trigger(t *testing.T, r *Runner, instanceID model.InstanceID) int {
+	t.Helper()
+	id, err := r.RunInstance(context.Background(), instanceID, nil)
+	if err != nil {
+		t.Fatalf("RunInstance(%s) error = %v", instanceID, err)
+	}
+	return id
+}
+
+// waitForStatus waits until the job has the given status and returns it
+func waitForStatus(t *testing.T, db *database.DB, jobStatusID int, want model.JobStatusState) *model.JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := db.GetJobByID(context.Background(), jobStatusID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != nil && status.Status == want {
+			return status
+		}
+		if time.Now().After(deadline) {
+			got := model.JobStatusState("<missing>")
+			if status != nil {
+				got = status.Status
+			}
+			t.Fatalf("job %d has status %q, want %q", jobStatusID, got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// errUpload is the error a fake run fails with
+var errUpload = errors.New("upload failed")
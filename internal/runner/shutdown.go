@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// shutdownCleanupTimeout bounds how long Stop waits for aborted jobs to run their cleanups
+const shutdownCleanupTimeout = time.Minute
+
+// errShutdown is the cancellation cause of runs aborted because Marina shut down
+var errShutdown = errors.New("aborted on shutdown")
+
+// Stop shuts the runner down: no new runs are started (cron ticks, deferred, catch-up and chained runs
+// are dropped; queued runs stay queued for the next start), and running jobs get until ctx is done to
+// finish. Jobs still running then are cancelled, run their normal cleanups and are marked aborted.
+func (r *Runner) Stop(ctx context.Context) {
+	// Cron callbacks still sleeping for their jitter are not waited for; startScheduled drops their runs
+	r.Cron.Stop()
+
+	r.mu.Lock()
+	r.stopping = true
+	running := len(r.running)
+	r.mu.Unlock()
+
+	if running == 0 {
+		return
+	}
+	r.Logger.Info("waiting for %d running job(s) to finish", running)
+	if r.waitForRuns(ctx) {
+		return
+	}
+
+	r.mu.Lock()
+	for id, job := range r.running {
+		r.Logger.Warn("aborting job %d of instance %s (shutdown timeout reached)", id, job.instanceID)
+		job.cancel(errShutdown)
+	}
+	r.mu.Unlock()
+
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), shutdownCleanupTimeout)
+	defer cancel()
+	if !r.waitForRuns(cleanupCtx) {
+		r.Logger.Error("cleanup of aborted jobs did not finish within %v", shutdownCleanupTimeout)
+	}
+}
+
+// waitForRuns waits until no job is running or ctx is done, and reports whether all jobs finished
+func (r *Runner) waitForRuns(ctx context.Context) bool {
+	finished := make(chan struct{})
+	go func() {
+		r.runs.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
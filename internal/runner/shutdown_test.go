@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// stopInBackground calls Stop with the given drain timeout and returns a channel closed when it returns.
+// It waits until the runner is stopping.
+func stopInBackground(t *testing.T, r *Runner, drain time.Duration) <-chan struct{} {
+	t.Helper()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ctx, cancel := context.WithTimeout(context.Background(), drain)
+		defer cancel()
+		r.Stop(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		stopping := r.stopping
+		r.mu.Unlock()
+		if stopping {
+			return stopped
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("runner did not start stopping")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func waitStopped(t *testing.T, stopped <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+}
+
+func TestStop_DrainsRunningJobs(t *testing.T) {
+	db := openTestDB(t)
+	r, runs := newTestRunner(t, db, map[model.InstanceID]backend.Backend{"app": newFakeBackend(backend.BackendTypeRestic)}, testSchedule("app"))
+
+	id := trigger(t, r, "app")
+	run := runs.expectStarted(t, "app")["app"]
+	stopped := stopInBackground(t, r, time.Minute)
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while a job was still running")
+	case <-time.After(100 * time.Millisecond):
+	}
+	run.finish <- nil
+	waitStopped(t, stopped)
+	waitForStatus(t, db, id, model.StatusSuccess)
+}
+
+func TestStop_AbortsJobsAfterTimeout(t *testing.T) {
+	db := openTestDB(t)
+	instances := map[model.InstanceID]backend.Backend{
+		"running": newFakeBackend(backend.BackendTypeRestic),
+		"queued":  newFakeBackend(backend.BackendTypeRestic),
+	}
+	r, runs := newTestRunner(t, db, instances, testSchedule("running"), testSchedule("queued"))
+	r.SetConcurrencyLimits(1, nil)
+
+	running := trigger(t, r, "running")
+	runs.expectStarted(t, "running")
+	queued := trigger(t, r, "queued")
+	waitForStatus(t, db, queued, model.StatusQueued)
+
+	stopped := stopInBackground(t, r, 100*time.Millisecond)
+
+	// New runs are rejected while the runner drains
+	if _, err := r.RunInstance(context.Background(), "queued", nil); !errors.Is(err, errShuttingDown) {
+		t.Errorf("RunInstance() while stopping error = %v, want %v", err, errShuttingDown)
+	}
+
+	waitStopped(t, stopped)
+	status := waitForStatus(t, db, running, model.StatusAborted)
+	if status.Message != errShutdown.Error() {
+		t.Errorf("message = %q, want %q", status.Message, errShutdown.Error())
+	}
+
+	// The queued run is neither started nor dropped: it is resumed after the restart
+	runs.expectNoStart(t)
+	waitForStatus(t, db, queued, model.StatusQueued)
+	if _, err := r.RunInstance(context.Background(), "queued", nil); !errors.Is(err, errShuttingDown) {
+		t.Errorf("RunInstance() after Stop error = %v, want %v", err, errShuttingDown)
+	}
+}
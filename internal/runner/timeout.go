@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/docker"
+	"github.com/polarfoxDev/marina/internal/logging"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// timeoutError is the cancellation cause of a run or one of its steps that exceeded its configured timeout
+type timeoutError struct {
+	phase   string // job, hook, dump or staging
+	target  string // target ID the step ran for ("" for the whole job)
+	timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	if e.target == "" {
+		return fmt.Sprintf("%s timed out after %s", e.phase, e.timeout)
+	}
+	return fmt.Sprintf("%s timed out after %s on target %s", e.phase, e.timeout, e.target)
+}
+
+// withTimeout limits a step to timeout; once it is exceeded, ctx is cancelled with a timeoutError
+// naming the phase and target. A timeout of 0 leaves the step unlimited.
+func withTimeout(ctx context.Context, phase, target string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout, &timeoutError{phase: phase, target: target, timeout: timeout})
+}
+
+// timeoutCause returns the timeout that cancelled ctx, if any
+func timeoutCause(ctx context.Context) (*timeoutError, bool) {
+	var timeoutErr *timeoutError
+	ok := errors.As(context.Cause(ctx), &timeoutErr)
+	return timeoutErr, ok
+}
+
+// explainTimeout reports the timeout that interrupted a step run with ctx instead of the bare
+// error it caused (e.g. "context deadline exceeded"), which is kept as detail
+func explainTimeout(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if timeoutErr, ok := timeoutCause(ctx); ok {
+		return fmt.Errorf("%w (%v)", timeoutErr, err)
+	}
+	return err
+}
+
+// runHook executes a pre- or post-hook (phase) in a container, limited to the instance's hook timeout
+func (r *Runner) runHook(ctx context.Context, phases *phaseTimeline, timeout time.Duration, targetID, containerID string, phase model.JobPhaseName, command string, jobLogger *logging.JobLogger) (err error) {
+	jobLogger.Debug("executing %s", phase)
+	endPhase := phases.start(ctx, targetID, phase)
+	defer func() { endPhase(err) }()
+	hookCtx, cancel := withTimeout(ctx, "hook", targetID, timeout)
+	defer cancel()
+	output, err := docker.ExecInContainer(hookCtx, r.Docker, containerID, []string{"/bin/sh", "-lc", command})
+	if err != nil {
+		return explainTimeout(hookCtx, err)
+	}
+	if output != "" {
+		jobLogger.Debug("%s output: %s", phase, output)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/docker/docker/api/types/container"
@@ -15,7 +16,7 @@ import (
 )
 
 // stageVolume prepares a volume for backup and returns the staged paths and cleanup function
-func (r *Runner) stageVolume(ctx context.Context, instanceID, timestamp string, target model.BackupTarget, jobLogger *logging.JobLogger) ([]string, cleanupFunc, error) {
+func (r *Runner) stageVolume(ctx context.Context, instanceID, timestamp string, helper model.HelperSettings, timeouts model.Timeouts, phases *phaseTimeline, target model.BackupTarget, jobLogger *logging.JobLogger) ([]string, cleanupFunc, error) {
 	// Look up volume from Docker to ensure it exists
 	volumeInfo, err := r.Docker.VolumeInspect(ctx, target.Name)
 	if err != nil {
@@ -25,97 +26,109 @@ func (r *Runner) stageVolume(ctx context.Context, instanceID, timestamp string,
 
 	// Find containers using this volume (for hooks and optional stopping)
 	var attachedCtrs []string
-	if target.PreHook != "" || target.PostHook != "" || target.StopAttached {
-		containers, err := r.Docker.ContainerList(ctx, container.ListOptions{All: true})
+	if target.PreHook != "" || target.PostHook != "" || quiesceEnabled(target.Quiesce) {
+		attachedCtrs, err = r.attachedContainers(ctx, target.Name)
 		if err != nil {
-			return nil, nil, fmt.Errorf("list containers: %w", err)
-		}
-		for _, c := range containers {
-			for _, m := range c.Mounts {
-				if m.Type == "volume" && m.Name == target.Name {
-					attachedCtrs = append(attachedCtrs, c.ID)
-					break
-				}
-			}
+			return nil, nil, err
 		}
 		jobLogger.Debug("found %d containers using volume %s", len(attachedCtrs), target.Name)
 	}
 
 	// Execute pre-hook in first attached container
+	var postHook func()
 	if target.PreHook != "" && len(attachedCtrs) > 0 {
-		jobLogger.Debug("executing pre-hook")
-		output, err := docker.ExecInContainer(ctx, r.Docker, attachedCtrs[0], []string{"/bin/sh", "-lc", target.PreHook})
-		if err != nil {
+		if err := r.runHook(ctx, phases, timeouts.Hook, target.ID, attachedCtrs[0], model.PhasePreHook, target.PreHook, jobLogger); err != nil {
 			return nil, nil, fmt.Errorf("prehook: %w", err)
 		}
-		if output != "" {
-			jobLogger.Debug("pre-hook output: %s", output)
-		}
-		// Defer post-hook
-		defer func() {
-			if target.PostHook != "" {
-				jobLogger.Debug("executing post-hook")
-				output, err := docker.ExecInContainer(ctx, r.Docker, attachedCtrs[0], []string{"/bin/sh", "-lc", target.PostHook})
-				if err != nil {
+		if target.PostHook != "" {
+			postHook = func() {
+				if err := r.runHook(context.WithoutCancel(ctx), phases, timeouts.Hook, target.ID, attachedCtrs[0], model.PhasePostHook, target.PostHook, jobLogger); err != nil {
 					jobLogger.Warn("post-hook failed: %v", err)
-				} else if output != "" {
-					jobLogger.Debug("post-hook output: %s", output)
 				}
 			}
-		}()
+		}
 	}
+	// Run post-hook once staging is done (direct staging hands it over to the cleanup function)
+	defer func() {
+		if postHook != nil {
+			postHook()
+		}
+	}()
 
-	// Stop attached containers if needed
-	var stoppedContainers []string
-	if target.StopAttached && len(attachedCtrs) > 0 {
-		for _, ctr := range attachedCtrs {
-			running, err := docker.IsContainerRunning(ctx, r.Docker, ctr)
-			if err != nil {
-				return nil, nil, fmt.Errorf("check container state: %w", err)
-			}
-			if !running {
-				continue
-			}
-
-			// Skip if mounted read-only
-			ctrInfo, err := r.Docker.ContainerInspect(ctx, ctr)
-			if err != nil {
-				return nil, nil, fmt.Errorf("inspect container: %w", err)
-			}
-			// Check if the target volume is mounted read-only in this container
-			skipStop := false
-			for _, m := range ctrInfo.Mounts {
-				if m.Type == "volume" && m.Name == target.Name && m.Mode == "ro" {
-					jobLogger.Info("container %s: volume %s is mounted read-only, skipping stop", ctr, target.Name)
-					skipStop = true
-					break
-				}
-			}
-			if skipStop {
-				continue
-			}
+	// The staging timeout covers quiescing the containers and copying or snapshotting the volume
+	stagingCtx, cancelStaging := withTimeout(ctx, "staging", target.ID, timeouts.Staging)
+	defer cancelStaging()
 
-			jobLogger.Info("stopping container %s", ctr)
-			if err := docker.StopContainer(ctx, r.Docker, ctr); err != nil {
-				return nil, nil, fmt.Errorf("stop container: %w", err)
-			}
-			stoppedContainers = append(stoppedContainers, ctr)
-		}
+	// Quiesce (stop or pause) attached containers if needed
+	endQuiesce := func(error) {}
+	if quiesceEnabled(target.Quiesce) {
+		endQuiesce = phases.start(ctx, target.ID, model.PhaseQuiesce)
+	}
+	quiescedContainers, err := r.quiesceContainers(stagingCtx, target, attachedCtrs, jobLogger)
+	err = explainTimeout(stagingCtx, err)
+	endQuiesce(err)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Copy volume data to staging
-	jobLogger.Info("copying volume %s to staging", target.Name)
-	stagedPaths, err := docker.CopyVolumeToStaging(ctx, r.Docker, r.HostBackupPath, instanceID, timestamp, target.Name, target.Paths, jobLogger)
+	// Stage volume data using the configured strategy
+	var stagedPaths []string
+	var releaseStaging func() // undoes staging side effects (e.g. snapshot mounts) before removal
+	endStaging := func(error) {}
+	if target.Staging != model.StagingDirect {
+		endStaging = phases.start(ctx, target.ID, model.PhaseStaging)
+	}
+	switch target.Staging {
+	case model.StagingDirect:
+		// Nothing to copy: the backend mounts the volume read-only and reads it in place
+		jobLogger.Info("backing up volume %s in place (direct)", target.Name)
+		stagedPaths = directVolumePaths(target.Name, target.Paths)
+	case model.StagingIncremental:
+		jobLogger.Info("syncing volume %s into its incremental staging copy", target.Name)
+		stagedPaths, releaseStaging, err = docker.SyncVolumeToStaging(stagingCtx, r.Docker, helper, r.HostBackupPath, instanceID, timestamp, target.Name, target.Paths, jobLogger)
+	case model.StagingSnapshot:
+		jobLogger.Info("snapshotting volume %s (%s) into staging", target.Name, target.Snapshot)
+		stagedPaths, releaseStaging, err = docker.SnapshotVolumeToStaging(stagingCtx, r.Docker, helper.Image, r.HostBackupPath, instanceID, timestamp, target.Name, volumeInfo.Mountpoint, target.Snapshot, target.Paths, jobLogger)
+	default:
+		jobLogger.Info("copying volume %s to staging", target.Name)
+		stagedPaths, err = docker.CopyVolumeToStaging(stagingCtx, r.Docker, helper, r.HostBackupPath, instanceID, timestamp, target.Name, target.Paths, jobLogger)
+	}
+	err = explainTimeout(stagingCtx, err)
+	endStaging(err)
+	if target.Staging != model.StagingDirect && (target.Quiesce == model.QuiescePause || target.Staging == model.StagingSnapshot) {
+		// Paused containers only need to be frozen for the copy itself, and a snapshot
+		// only needs them quiesced for the snapshot instant, so release them right away -
+		// whether staging succeeded or not
+		r.releaseContainers(ctx, target.Quiesce, quiescedContainers, jobLogger)
+		quiescedContainers = nil
+	}
 	if err != nil {
 		// Restart stopped containers before returning error
-		for _, ctr := range stoppedContainers {
-			_ = docker.StartContainer(ctx, r.Docker, ctr)
-		}
+		r.releaseContainers(ctx, target.Quiesce, quiescedContainers, jobLogger)
 		return nil, nil, err
 	}
 
+	if target.Staging == model.StagingDirect {
+		// With direct staging the volume is read during the upload, so the post-hook
+		// and container release are part of the cleanup
+		deferredPostHook := postHook
+		postHook = nil
+		return stagedPaths, func() {
+			if deferredPostHook != nil {
+				deferredPostHook()
+			}
+			r.releaseContainers(ctx, target.Quiesce, quiescedContainers, jobLogger)
+		}, nil
+	}
+
 	// Create cleanup function
 	cleanup := func() {
+		// Unmount/destroy snapshots or move incremental copies back first,
+		// so only the (empty) mount point gets removed
+		if releaseStaging != nil {
+			releaseStaging()
+		}
+
 		// Clean up staging directory
 		if len(stagedPaths) > 0 {
 			firstPath := stagedPaths[0]
@@ -139,10 +152,7 @@ func (r *Runner) stageVolume(ctx context.Context, instanceID, timestamp string,
 		}
 
 		// Restart stopped containers
-		for _, ctr := range stoppedContainers {
-			jobLogger.Info("restarting container %s", ctr)
-			_ = docker.StartContainer(ctx, r.Docker, ctr)
-		}
+		r.releaseContainers(ctx, target.Quiesce, quiescedContainers, jobLogger)
 	}
 
 	// Validate staged files have content
@@ -155,3 +165,122 @@ func (r *Runner) stageVolume(ctx context.Context, instanceID, timestamp string,
 
 	return stagedPaths, cleanup, nil
 }
+
+// directVolumeMountPath returns where a volume is mounted inside the restic helper container
+// used for direct staging
+func directVolumeMountPath(volumeName string) string {
+	return filepath.Join("/volumes", volumeName)
+}
+
+// directVolumePaths maps the configured paths of a volume to its mount in the restic helper container
+func directVolumePaths(volumeName string, paths []string) []string {
+	mountPath := directVolumeMountPath(volumeName)
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		result = append(result, filepath.Join(mountPath, strings.TrimPrefix(path, "/")))
+	}
+	return result
+}
+
+// quiesceEnabled reports whether a quiesce mode requires touching attached containers
+func quiesceEnabled(mode model.QuiesceMode) bool {
+	return mode == model.QuiesceStop || mode == model.QuiescePause
+}
+
+// attachedContainers returns the IDs of all containers (running or not) that mount a volume
+func (r *Runner) attachedContainers(ctx context.Context, volumeName string) ([]string, error) {
+	containers, err := r.Docker.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	var attached []string
+	for _, c := range containers {
+		for _, m := range c.Mounts {
+			if m.Type == "volume" && m.Name == volumeName {
+				attached = append(attached, c.ID)
+				break
+			}
+		}
+	}
+	return attached, nil
+}
+
+// containersToQuiesce selects the attached containers quiesceContainers would stop or pause:
+// running containers that mount the volume writable
+func (r *Runner) containersToQuiesce(ctx context.Context, target model.BackupTarget, attachedCtrs []string, jobLogger *logging.JobLogger) ([]string, error) {
+	var selected []string
+	for _, ctr := range attachedCtrs {
+		ctrInfo, err := r.Docker.ContainerInspect(ctx, ctr)
+		if err != nil {
+			return nil, fmt.Errorf("inspect container: %w", err)
+		}
+		if ctrInfo.State == nil || !ctrInfo.State.Running || ctrInfo.State.Paused {
+			// Not running, or already paused by someone else - leave it alone
+			continue
+		}
+
+		// Skip if the target volume is mounted read-only in this container
+		readOnly := slices.ContainsFunc(ctrInfo.Mounts, func(m container.MountPoint) bool {
+			return m.Type == "volume" && m.Name == target.Name && m.Mode == "ro"
+		})
+		if readOnly {
+			jobLogger.Info("container %s: volume %s is mounted read-only, skipping %s", ctr, target.Name, target.Quiesce)
+			continue
+		}
+		selected = append(selected, ctr)
+	}
+	return selected, nil
+}
+
+// quiesceContainers stops or pauses the running containers attached to a volume target.
+// Containers that mount the volume read-only are skipped. Returns the containers that were
+// quiesced; on error, containers quiesced so far are released before returning.
+func (r *Runner) quiesceContainers(ctx context.Context, target model.BackupTarget, attachedCtrs []string, jobLogger *logging.JobLogger) ([]string, error) {
+	if !quiesceEnabled(target.Quiesce) {
+		return nil, nil
+	}
+
+	selected, err := r.containersToQuiesce(ctx, target, attachedCtrs, jobLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	var quiesced []string
+	for _, ctr := range selected {
+		var err error
+		switch target.Quiesce {
+		case model.QuiescePause:
+			jobLogger.Info("pausing container %s", ctr)
+			err = docker.PauseContainer(ctx, r.Docker, ctr)
+		default:
+			jobLogger.Info("stopping container %s", ctr)
+			err = docker.StopContainer(ctx, r.Docker, ctr)
+		}
+		if err != nil {
+			r.releaseContainers(ctx, target.Quiesce, quiesced, jobLogger)
+			return nil, fmt.Errorf("%s container: %w", target.Quiesce, err)
+		}
+		quiesced = append(quiesced, ctr)
+	}
+	return quiesced, nil
+}
+
+// releaseContainers undoes quiesceContainers by unpausing or restarting the given containers.
+// It deliberately ignores cancellation of ctx so that containers are never left frozen.
+func (r *Runner) releaseContainers(ctx context.Context, mode model.QuiesceMode, containers []string, jobLogger *logging.JobLogger) {
+	releaseCtx := context.WithoutCancel(ctx)
+	for _, ctr := range containers {
+		switch mode {
+		case model.QuiescePause:
+			jobLogger.Info("unpausing container %s", ctr)
+			if err := docker.UnpauseContainer(releaseCtx, r.Docker, ctr); err != nil {
+				jobLogger.Warn("failed to unpause container %s: %v", ctr, err)
+			}
+		default:
+			jobLogger.Info("restarting container %s", ctr)
+			if err := docker.StartContainer(releaseCtx, r.Docker, ctr); err != nil {
+				jobLogger.Warn("failed to restart container %s: %v", ctr, err)
+			}
+		}
+	}
+}
@@ -2,7 +2,9 @@ package scheduler
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/polarfoxDev/marina/internal/config"
 	"github.com/polarfoxDev/marina/internal/helpers"
@@ -10,21 +12,30 @@ import (
 )
 
 // BuildSchedulesFromConfig converts config instances to backup schedules
-// Targets are created with defaults; validation happens during staging
+// Targets are created with defaults; validation happens during staging.
+// Targets of an instance are grouped by their effective cron schedule (target schedule > instance
+// schedule), so an instance yields one schedule per group; targets without any schedule are not scheduled,
+// unless the instance is chained ('after'), which yields a single schedule without cron.
 func BuildSchedulesFromConfig(cfg *config.Config) ([]model.InstanceBackupSchedule, error) {
 	var schedules []model.InstanceBackupSchedule
 
 	for _, inst := range cfg.Instances {
-		// Validate schedule
-		if inst.Schedule == "" {
-			continue
+		// Use instance time zone or global fallback
+		timezone := inst.Timezone
+		if timezone == "" {
+			timezone = cfg.Timezone
 		}
-		if err := helpers.ValidateCron(inst.Schedule); err != nil {
-			return nil, fmt.Errorf("invalid schedule for instance %s: %w", inst.ID, err)
+
+		// Validate schedule
+		if inst.Schedule != "" {
+			if err := helpers.ValidateCron(helpers.CronSpec(inst.Schedule, timezone)); err != nil {
+				return nil, fmt.Errorf("invalid schedule for instance %s: %w", inst.ID, err)
+			}
 		}
 
 		// Build targets from config (without Docker validation)
 		var targets []model.BackupTarget
+		var targetCrons []string // effective schedule of each target in targets
 		for i, targetCfg := range inst.Targets {
 			// Validate target configuration
 			hasVolume := targetCfg.Volume != ""
@@ -37,12 +48,20 @@ func BuildSchedulesFromConfig(cfg *config.Config) ([]model.InstanceBackupSchedul
 				return nil, fmt.Errorf("instance %s target #%d: must specify either 'volume' or 'db'", inst.ID, i+1)
 			}
 
-			// Determine stopAttached: target config > global config > default (false)
-			stopAttached := false
-			if targetCfg.StopAttached != nil {
-				stopAttached = *targetCfg.StopAttached
-			} else if cfg.StopAttached != nil {
-				stopAttached = *cfg.StopAttached
+			targetCron := inst.Schedule
+			if targetCfg.Schedule != "" {
+				if err := helpers.ValidateCron(helpers.CronSpec(targetCfg.Schedule, timezone)); err != nil {
+					return nil, fmt.Errorf("instance %s target #%d: invalid schedule: %w", inst.ID, i+1, err)
+				}
+				targetCron = targetCfg.Schedule
+			}
+			if targetCron == "" && len(inst.After) == 0 {
+				continue
+			}
+
+			quiesce, err := resolveQuiesce(cfg, targetCfg)
+			if err != nil {
+				return nil, fmt.Errorf("instance %s target #%d: %w", inst.ID, i+1, err)
 			}
 
 			// Apply defaults for paths
@@ -52,21 +71,49 @@ func BuildSchedulesFromConfig(cfg *config.Config) ([]model.InstanceBackupSchedul
 			}
 
 			if targetCfg.Volume != "" {
+				staging, snapshot, err := resolveStaging(targetCfg)
+				if err != nil {
+					return nil, fmt.Errorf("instance %s target #%d: %w", inst.ID, i+1, err)
+				}
+				if staging == model.StagingDirect && inst.CustomImage != "" {
+					return nil, fmt.Errorf("instance %s target #%d: staging: direct is only supported for restic repositories", inst.ID, i+1)
+				}
+				if staging == model.StagingDirect && quiesce == model.QuiescePause {
+					// The volume is read during the upload, so paused containers would stay frozen until it ends
+					return nil, fmt.Errorf("instance %s target #%d: quiesce: pause cannot be combined with staging: direct (use stop or none)", inst.ID, i+1)
+				}
+
 				// Volume backup target
 				target := model.BackupTarget{
-					ID:           "volume:" + targetCfg.Volume,
-					Name:         targetCfg.Volume,
-					Type:         model.TargetVolume,
-					InstanceID:   model.InstanceID(inst.ID),
-					PreHook:      targetCfg.PreHook,
-					PostHook:     targetCfg.PostHook,
-					Paths:        paths,
-					StopAttached: stopAttached,
+					ID:         "volume:" + targetCfg.Volume,
+					Name:       targetCfg.Volume,
+					Type:       model.TargetVolume,
+					InstanceID: model.InstanceID(inst.ID),
+					PreHook:    targetCfg.PreHook,
+					PostHook:   targetCfg.PostHook,
+					Paths:      paths,
+					Quiesce:    quiesce,
+					Staging:    staging,
+					Snapshot:   snapshot,
 					// AttachedCtrs will be resolved during staging
 				}
 				targets = append(targets, target)
+				targetCrons = append(targetCrons, targetCron)
 
 			} else if targetCfg.DB != "" {
+				staging := model.StagingMode(strings.ToLower(targetCfg.Staging))
+				switch staging {
+				case "":
+					staging = model.StagingCopy
+				case model.StagingCopy:
+				case model.StagingStream:
+					if inst.CustomImage != "" {
+						return nil, fmt.Errorf("instance %s target #%d: staging: stream is only supported for restic repositories", inst.ID, i+1)
+					}
+				default:
+					return nil, fmt.Errorf("instance %s target #%d: staging %q is not supported for database targets (must be copy or stream)", inst.ID, i+1, targetCfg.Staging)
+				}
+
 				// Database backup target
 				target := model.BackupTarget{
 					ID:         "db:" + targetCfg.DB,
@@ -77,9 +124,11 @@ func BuildSchedulesFromConfig(cfg *config.Config) ([]model.InstanceBackupSchedul
 					PostHook:   targetCfg.PostHook,
 					DBKind:     strings.ToLower(targetCfg.DBKind), // may be empty, will auto-detect during staging
 					DumpArgs:   targetCfg.DumpArgs,
+					Staging:    staging,
 					// ContainerID will be resolved during staging
 				}
 				targets = append(targets, target)
+				targetCrons = append(targetCrons, targetCron)
 			}
 		}
 
@@ -94,14 +143,281 @@ func BuildSchedulesFromConfig(cfg *config.Config) ([]model.InstanceBackupSchedul
 			retention = cfg.Retention
 		}
 
-		schedule := model.InstanceBackupSchedule{
-			InstanceID:   model.InstanceID(inst.ID),
-			ScheduleCron: inst.Schedule,
-			Targets:      targets,
-			Retention:    helpers.ParseRetention(retention),
+		helper, err := resolveHelper(cfg.Helper, inst.Helper)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", inst.ID, err)
+		}
+
+		overlap, err := resolveOverlap(inst.Overlap)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", inst.ID, err)
+		}
+
+		retry, err := resolveRetry(inst.Retry)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", inst.ID, err)
+		}
+
+		jitter, err := resolveJitter(cfg.Jitter, inst.Jitter)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", inst.ID, err)
+		}
+
+		timeouts, err := resolveTimeouts(cfg, inst)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", inst.ID, err)
+		}
+
+		blackouts, err := resolveBlackouts(append(slices.Clone(cfg.Blackouts), inst.Blackouts...), timezone)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", inst.ID, err)
+		}
+
+		// Chained instances have no schedule: all targets form one group started by the upstream instances
+		var after []model.InstanceID
+		for _, upstream := range inst.After {
+			after = append(after, model.InstanceID(upstream))
+		}
+
+		// One schedule per cron group, in order of first appearance
+		var crons []string
+		groups := make(map[string][]model.BackupTarget)
+		for i, target := range targets {
+			if _, ok := groups[targetCrons[i]]; !ok {
+				crons = append(crons, targetCrons[i])
+			}
+			groups[targetCrons[i]] = append(groups[targetCrons[i]], target)
+		}
+		for _, cron := range crons {
+			schedule := model.InstanceBackupSchedule{
+				InstanceID:   model.InstanceID(inst.ID),
+				ScheduleCron: cron,
+				Timezone:     timezone,
+				Jitter:       jitter,
+				Targets:      groups[cron],
+				Retention:    helpers.ParseRetention(retention),
+				Helper:       helper,
+				Overlap:      overlap,
+				Retry:        retry,
+				Timeouts:     timeouts,
+				CatchUp:      inst.CatchUp,
+				Blackouts:    blackouts,
+				After:        after,
+			}
+			schedules = append(schedules, schedule)
 		}
-		schedules = append(schedules, schedule)
 	}
 
 	return schedules, nil
 }
+
+// resolveQuiesce determines the quiesce mode for a volume target.
+// Precedence: target quiesce > target stopAttached > global stopAttached > none
+func resolveQuiesce(cfg *config.Config, targetCfg config.TargetConfig) (model.QuiesceMode, error) {
+	if targetCfg.Quiesce != "" {
+		mode := model.QuiesceMode(strings.ToLower(targetCfg.Quiesce))
+		switch mode {
+		case model.QuiesceNone, model.QuiesceStop, model.QuiescePause:
+			return mode, nil
+		default:
+			return "", fmt.Errorf("invalid quiesce mode %q (must be pause, stop or none)", targetCfg.Quiesce)
+		}
+	}
+
+	stopAttached := false
+	if targetCfg.StopAttached != nil {
+		stopAttached = *targetCfg.StopAttached
+	} else if cfg.StopAttached != nil {
+		stopAttached = *cfg.StopAttached
+	}
+	if stopAttached {
+		return model.QuiesceStop, nil
+	}
+	return model.QuiesceNone, nil
+}
+
+// resolveOverlap validates an instance's overlap policy (default: skip)
+func resolveOverlap(value string) (model.OverlapPolicy, error) {
+	policy := model.OverlapPolicy(strings.ToLower(value))
+	switch policy {
+	case "":
+		return model.OverlapSkip, nil
+	case model.OverlapSkip, model.OverlapQueue, model.OverlapCancelPrevious:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid overlap policy %q (must be skip, queue or cancel-previous)", value)
+	}
+}
+
+// resolveRetry validates an instance's retry policy and applies defaults
+// (1 attempt, 30s initial backoff, multiplier 2)
+func resolveRetry(retryCfg config.RetryConfig) (model.RetryPolicy, error) {
+	policy := model.RetryPolicy{MaxAttempts: 1, InitialBackoff: 30 * time.Second, Multiplier: 2}
+
+	if retryCfg.MaxAttempts < 0 {
+		return policy, fmt.Errorf("invalid retry maxAttempts %d (must be at least 1)", retryCfg.MaxAttempts)
+	}
+	if retryCfg.MaxAttempts > 0 {
+		policy.MaxAttempts = retryCfg.MaxAttempts
+	}
+	if retryCfg.InitialBackoff != "" {
+		backoff, err := time.ParseDuration(retryCfg.InitialBackoff)
+		if err != nil || backoff < 0 {
+			return policy, fmt.Errorf("invalid retry initialBackoff %q", retryCfg.InitialBackoff)
+		}
+		policy.InitialBackoff = backoff
+	}
+	if retryCfg.Multiplier != 0 {
+		if retryCfg.Multiplier < 1 {
+			return policy, fmt.Errorf("invalid retry multiplier %v (must be at least 1)", retryCfg.Multiplier)
+		}
+		policy.Multiplier = retryCfg.Multiplier
+	}
+	return policy, nil
+}
+
+// resolveJitter determines the maximum random delay of scheduled runs (instance > global > none)
+func resolveJitter(global, instance string) (time.Duration, error) {
+	value := instance
+	if value == "" {
+		value = global
+	}
+	if value == "" {
+		return 0, nil
+	}
+	jitter, err := time.ParseDuration(value)
+	if err != nil || jitter < 0 {
+		return 0, fmt.Errorf("invalid jitter %q (must be a duration like 5m)", value)
+	}
+	return jitter, nil
+}
+
+// weekdays maps abbreviated day names; blackout windows accept them and the full names
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// resolveBlackouts validates blackout windows (global ones first, then the instance's) and evaluates
+// them in the instance's time zone
+func resolveBlackouts(windows []config.BlackoutConfig, timezone string) ([]model.BlackoutWindow, error) {
+	var blackouts []model.BlackoutWindow
+	for i, windowCfg := range windows {
+		window := model.BlackoutWindow{Timezone: timezone, Policy: model.BlackoutPolicy(strings.ToLower(windowCfg.Policy))}
+		switch window.Policy {
+		case "":
+			window.Policy = model.BlackoutDefer
+		case model.BlackoutDefer, model.BlackoutSkip:
+		default:
+			return nil, fmt.Errorf("blackout #%d: invalid policy %q (must be defer or skip)", i+1, windowCfg.Policy)
+		}
+
+		isCron := windowCfg.Cron != "" || windowCfg.Duration != ""
+		isRange := windowCfg.Start != "" || windowCfg.End != "" || len(windowCfg.Days) > 0
+		switch {
+		case isCron && isRange:
+			return nil, fmt.Errorf("blackout #%d: cannot combine 'cron'/'duration' with 'days'/'start'/'end'", i+1)
+		case isCron:
+			if err := helpers.ValidateCron(helpers.CronSpec(windowCfg.Cron, timezone)); err != nil {
+				return nil, fmt.Errorf("blackout #%d: %w", i+1, err)
+			}
+			duration, err := time.ParseDuration(windowCfg.Duration)
+			if err != nil || duration <= 0 {
+				return nil, fmt.Errorf("blackout #%d: invalid duration %q", i+1, windowCfg.Duration)
+			}
+			window.Cron = windowCfg.Cron
+			window.Duration = duration
+		case isRange:
+			start, err := parseTimeOfDay(windowCfg.Start)
+			if err != nil {
+				return nil, fmt.Errorf("blackout #%d: invalid start: %w", i+1, err)
+			}
+			end, err := parseTimeOfDay(windowCfg.End)
+			if err != nil {
+				return nil, fmt.Errorf("blackout #%d: invalid end: %w", i+1, err)
+			}
+			window.Start = start
+			window.End = end
+			for _, day := range windowCfg.Days {
+				name := strings.ToLower(day)
+				weekday, ok := weekdays[name[:min(3, len(name))]]
+				if !ok || !strings.HasPrefix(strings.ToLower(weekday.String()), name) {
+					return nil, fmt.Errorf("blackout #%d: invalid day %q", i+1, day)
+				}
+				window.Days = append(window.Days, weekday)
+			}
+		default:
+			return nil, fmt.Errorf("blackout #%d: must specify either 'cron' and 'duration' or 'start' and 'end'", i+1)
+		}
+		blackouts = append(blackouts, window)
+	}
+	return blackouts, nil
+}
+
+// parseTimeOfDay parses "HH:MM" into an offset from midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day (HH:MM)", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// resolveHelper merges the instance and global helper container settings (per field: instance > global > default)
+func resolveHelper(global, instance config.HelperConfig) (model.HelperSettings, error) {
+	settings := model.HelperSettings{Image: model.DefaultHelperImage}
+	if global.Image != "" {
+		settings.Image = global.Image
+	}
+	if instance.Image != "" {
+		settings.Image = instance.Image
+	}
+
+	settings.CPUs = global.CPUs
+	if instance.CPUs != 0 {
+		settings.CPUs = instance.CPUs
+	}
+	if settings.CPUs < 0 {
+		return settings, fmt.Errorf("invalid helper cpus %v (must be positive)", settings.CPUs)
+	}
+
+	memory := global.Memory
+	if instance.Memory != "" {
+		memory = instance.Memory
+	}
+	if memory != "" {
+		bytes, err := helpers.ParseByteSize(memory)
+		if err != nil {
+			return settings, fmt.Errorf("invalid helper memory: %w", err)
+		}
+		settings.Memory = bytes
+	}
+	return settings, nil
+}
+
+// resolveStaging determines the staging strategy and snapshot driver for a volume target
+func resolveStaging(targetCfg config.TargetConfig) (model.StagingMode, string, error) {
+	staging := model.StagingMode(strings.ToLower(targetCfg.Staging))
+	snapshot := strings.ToLower(targetCfg.Snapshot)
+	switch staging {
+	case "", model.StagingCopy, model.StagingDirect, model.StagingIncremental:
+		if snapshot != "" {
+			return "", "", fmt.Errorf("snapshot driver %q requires staging: snapshot", targetCfg.Snapshot)
+		}
+		if staging == "" {
+			staging = model.StagingCopy
+		}
+		return staging, "", nil
+	case model.StagingSnapshot:
+		switch snapshot {
+		case model.SnapshotBtrfs, model.SnapshotZFS, model.SnapshotLVM:
+			return staging, snapshot, nil
+		case "":
+			return "", "", fmt.Errorf("staging: snapshot requires a snapshot driver (btrfs, zfs or lvm)")
+		default:
+			return "", "", fmt.Errorf("invalid snapshot driver %q (must be btrfs, zfs or lvm)", targetCfg.Snapshot)
+		}
+	default:
+		return "", "", fmt.Errorf("invalid staging mode %q (must be copy, snapshot, direct or incremental)", targetCfg.Staging)
+	}
+}
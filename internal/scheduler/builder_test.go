@@ -1,10 +1,13 @@
 package scheduler
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/polarfoxDev/marina/internal/config"
+	"github.com/polarfoxDev/marina/internal/model"
 )
 
 func TestBuildSchedulesFromConfig_ValidatesTargets(t *testing.T) {
@@ -124,3 +127,649 @@ func TestBuildSchedulesFromConfig_ValidatesTargets(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildSchedulesFromConfig_Quiesce(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+
+	tests := []struct {
+		name         string
+		globalStop   *bool
+		target       config.TargetConfig
+		expected     model.QuiesceMode
+		expectError  bool
+		errorMessage string
+	}{
+		{
+			name:     "default is none",
+			target:   config.TargetConfig{Volume: "data"},
+			expected: model.QuiesceNone,
+		},
+		{
+			name:     "target stopAttached maps to stop",
+			target:   config.TargetConfig{Volume: "data", StopAttached: boolPtr(true)},
+			expected: model.QuiesceStop,
+		},
+		{
+			name:       "global stopAttached maps to stop",
+			globalStop: boolPtr(true),
+			target:     config.TargetConfig{Volume: "data"},
+			expected:   model.QuiesceStop,
+		},
+		{
+			name:       "quiesce overrides stopAttached",
+			globalStop: boolPtr(true),
+			target:     config.TargetConfig{Volume: "data", StopAttached: boolPtr(true), Quiesce: "pause"},
+			expected:   model.QuiescePause,
+		},
+		{
+			name:     "quiesce is case-insensitive",
+			target:   config.TargetConfig{Volume: "data", Quiesce: "NONE"},
+			expected: model.QuiesceNone,
+		},
+		{
+			name:         "invalid quiesce mode",
+			target:       config.TargetConfig{Volume: "data", Quiesce: "freeze"},
+			expectError:  true,
+			errorMessage: "invalid quiesce mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				StopAttached: tt.globalStop,
+				Instances: []config.BackupInstance{
+					{ID: "test", Schedule: "0 2 * * *", Targets: []config.TargetConfig{tt.target}},
+				},
+			}
+			schedules, err := BuildSchedulesFromConfig(cfg)
+			if tt.expectError {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedules[0].Targets[0].Quiesce; got != tt.expected {
+				t.Errorf("expected quiesce %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildSchedulesFromConfig_Staging(t *testing.T) {
+	tests := []struct {
+		name             string
+		target           config.TargetConfig
+		customImage      string
+		expectedStaging  model.StagingMode
+		expectedSnapshot string
+		errorMessage     string
+	}{
+		{
+			name:            "default is copy",
+			target:          config.TargetConfig{Volume: "data"},
+			expectedStaging: model.StagingCopy,
+		},
+		{
+			name:             "zfs snapshot",
+			target:           config.TargetConfig{Volume: "data", Staging: "snapshot", Snapshot: "ZFS"},
+			expectedStaging:  model.StagingSnapshot,
+			expectedSnapshot: model.SnapshotZFS,
+		},
+		{
+			name:            "direct",
+			target:          config.TargetConfig{Volume: "data", Staging: "direct"},
+			expectedStaging: model.StagingDirect,
+		},
+		{
+			name:            "incremental",
+			target:          config.TargetConfig{Volume: "data", Staging: "Incremental"},
+			expectedStaging: model.StagingIncremental,
+		},
+		{
+			name:         "direct with custom image",
+			target:       config.TargetConfig{Volume: "data", Staging: "direct"},
+			customImage:  "example/backup:latest",
+			errorMessage: "only supported for restic repositories",
+		},
+		{
+			name:         "direct with pause",
+			target:       config.TargetConfig{Volume: "data", Staging: "direct", Quiesce: "pause"},
+			errorMessage: "cannot be combined with staging: direct",
+		},
+		{
+			name:            "direct with stop",
+			target:          config.TargetConfig{Volume: "data", Staging: "direct", Quiesce: "stop"},
+			expectedStaging: model.StagingDirect,
+		},
+		{
+			name:         "snapshot without driver",
+			target:       config.TargetConfig{Volume: "data", Staging: "snapshot"},
+			errorMessage: "requires a snapshot driver",
+		},
+		{
+			name:         "unknown snapshot driver",
+			target:       config.TargetConfig{Volume: "data", Staging: "snapshot", Snapshot: "ext4"},
+			errorMessage: "invalid snapshot driver",
+		},
+		{
+			name:         "driver without snapshot staging",
+			target:       config.TargetConfig{Volume: "data", Snapshot: "btrfs"},
+			errorMessage: "requires staging: snapshot",
+		},
+		{
+			name:         "unknown staging mode",
+			target:       config.TargetConfig{Volume: "data", Staging: "magic"},
+			errorMessage: "invalid staging mode",
+		},
+		{
+			name:            "stream database",
+			target:          config.TargetConfig{DB: "postgres", Staging: "stream"},
+			expectedStaging: model.StagingStream,
+		},
+		{
+			name:         "stream database with custom image",
+			target:       config.TargetConfig{DB: "postgres", Staging: "stream"},
+			customImage:  "example/backup:latest",
+			errorMessage: "only supported for restic repositories",
+		},
+		{
+			name:         "stream volume",
+			target:       config.TargetConfig{Volume: "data", Staging: "stream"},
+			errorMessage: "invalid staging mode",
+		},
+		{
+			name:         "snapshot staging for database",
+			target:       config.TargetConfig{DB: "postgres", Staging: "snapshot"},
+			errorMessage: "not supported for database targets",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Instances: []config.BackupInstance{
+					{ID: "test", Schedule: "0 2 * * *", CustomImage: tt.customImage, Targets: []config.TargetConfig{tt.target}},
+				},
+			}
+			schedules, err := BuildSchedulesFromConfig(cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			target := schedules[0].Targets[0]
+			if target.Staging != tt.expectedStaging || target.Snapshot != tt.expectedSnapshot {
+				t.Errorf("expected staging %q/%q, got %q/%q", tt.expectedStaging, tt.expectedSnapshot, target.Staging, target.Snapshot)
+			}
+		})
+	}
+}
+
+func TestBuildSchedulesFromConfig_Helper(t *testing.T) {
+	tests := []struct {
+		name         string
+		global       config.HelperConfig
+		instance     config.HelperConfig
+		expected     model.HelperSettings
+		errorMessage string
+	}{
+		{
+			name:     "defaults",
+			expected: model.HelperSettings{Image: model.DefaultHelperImage},
+		},
+		{
+			name:     "global settings",
+			global:   config.HelperConfig{Image: "registry.local/alpine:3.20", CPUs: 1, Memory: "256m"},
+			expected: model.HelperSettings{Image: "registry.local/alpine:3.20", CPUs: 1, Memory: 256 << 20},
+		},
+		{
+			name:     "instance overrides per field",
+			global:   config.HelperConfig{Image: "registry.local/alpine:3.20", CPUs: 1, Memory: "256m"},
+			instance: config.HelperConfig{CPUs: 0.25, Memory: "1g"},
+			expected: model.HelperSettings{Image: "registry.local/alpine:3.20", CPUs: 0.25, Memory: 1 << 30},
+		},
+		{
+			name:         "invalid memory",
+			instance:     config.HelperConfig{Memory: "lots"},
+			errorMessage: "invalid helper memory",
+		},
+		{
+			name:         "negative cpus",
+			global:       config.HelperConfig{CPUs: -1},
+			errorMessage: "invalid helper cpus",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Helper: tt.global,
+				Instances: []config.BackupInstance{
+					{ID: "test", Schedule: "0 2 * * *", Helper: tt.instance, Targets: []config.TargetConfig{{Volume: "data"}}},
+				},
+			}
+			schedules, err := BuildSchedulesFromConfig(cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedules[0].Helper; got != tt.expected {
+				t.Errorf("expected helper %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildSchedulesFromConfig_Overlap(t *testing.T) {
+	tests := []struct {
+		name         string
+		overlap      string
+		expected     model.OverlapPolicy
+		errorMessage string
+	}{
+		{name: "default skip", expected: model.OverlapSkip},
+		{name: "queue", overlap: "queue", expected: model.OverlapQueue},
+		{name: "cancel previous", overlap: "Cancel-Previous", expected: model.OverlapCancelPrevious},
+		{name: "invalid", overlap: "parallel", errorMessage: "invalid overlap policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Instances: []config.BackupInstance{
+					{ID: "test", Schedule: "0 2 * * *", Overlap: tt.overlap, Targets: []config.TargetConfig{{Volume: "data"}}},
+				},
+			}
+			schedules, err := BuildSchedulesFromConfig(cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedules[0].Overlap; got != tt.expected {
+				t.Errorf("expected overlap %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildSchedulesFromConfig_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		retry        config.RetryConfig
+		expected     model.RetryPolicy
+		errorMessage string
+	}{
+		{
+			name:     "defaults",
+			expected: model.RetryPolicy{MaxAttempts: 1, InitialBackoff: 30 * time.Second, Multiplier: 2},
+		},
+		{
+			name:     "custom",
+			retry:    config.RetryConfig{MaxAttempts: 4, InitialBackoff: "1m", Multiplier: 1.5},
+			expected: model.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Minute, Multiplier: 1.5},
+		},
+		{
+			name:         "negative attempts",
+			retry:        config.RetryConfig{MaxAttempts: -1},
+			errorMessage: "invalid retry maxAttempts",
+		},
+		{
+			name:         "invalid backoff",
+			retry:        config.RetryConfig{InitialBackoff: "soon"},
+			errorMessage: "invalid retry initialBackoff",
+		},
+		{
+			name:         "shrinking backoff",
+			retry:        config.RetryConfig{Multiplier: 0.5},
+			errorMessage: "invalid retry multiplier",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Instances: []config.BackupInstance{
+					{ID: "test", Schedule: "0 2 * * *", Retry: tt.retry, Targets: []config.TargetConfig{{Volume: "data"}}},
+				},
+			}
+			schedules, err := BuildSchedulesFromConfig(cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedules[0].Retry; got != tt.expected {
+				t.Errorf("expected retry %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildSchedulesFromConfig_TargetSchedules(t *testing.T) {
+	cfg := &config.Config{
+		Instances: []config.BackupInstance{
+			{
+				ID:       "app",
+				Schedule: "0 3 * * *",
+				Targets: []config.TargetConfig{
+					{DB: "postgres", Schedule: "0 * * * *"},
+					{Volume: "media", Schedule: "0 4 * * 0"},
+					{Volume: "config"},
+					{DB: "redis", Schedule: "0 * * * *"},
+				},
+			},
+			{
+				// No instance schedule: only targets with their own schedule are scheduled
+				ID: "partial",
+				Targets: []config.TargetConfig{
+					{Volume: "cache"},
+					{Volume: "uploads", Schedule: "30 1 * * *"},
+				},
+			},
+			{ID: "unscheduled", Targets: []config.TargetConfig{{Volume: "tmp"}}},
+		},
+	}
+
+	schedules, err := BuildSchedulesFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		key     string
+		targets []string
+	}{
+		{"app@0 * * * *", []string{"db:postgres", "db:redis"}},
+		{"app@0 4 * * 0", []string{"volume:media"}},
+		{"app@0 3 * * *", []string{"volume:config"}},
+		{"partial@30 1 * * *", []string{"volume:uploads"}},
+	}
+	if len(schedules) != len(expected) {
+		t.Fatalf("expected %d schedules, got %d: %+v", len(expected), len(schedules), schedules)
+	}
+	for i, exp := range expected {
+		if got := schedules[i].Key(); got != exp.key {
+			t.Errorf("schedule %d: expected key %q, got %q", i, exp.key, got)
+		}
+		var ids []string
+		for _, target := range schedules[i].Targets {
+			ids = append(ids, target.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(exp.targets, ",") {
+			t.Errorf("schedule %d: expected targets %v, got %v", i, exp.targets, ids)
+		}
+	}
+
+	cfg.Instances[0].Targets[0].Schedule = "every hour"
+	if _, err := BuildSchedulesFromConfig(cfg); err == nil || !strings.Contains(err.Error(), "target #1: invalid schedule") {
+		t.Errorf("expected invalid target schedule error, got %v", err)
+	}
+}
+
+func TestBuildSchedulesFromConfig_TimezoneAndJitter(t *testing.T) {
+	tests := []struct {
+		name             string
+		globalTimezone   string
+		globalJitter     string
+		timezone         string
+		jitter           string
+		expectedTimezone string
+		expectedJitter   time.Duration
+		errorMessage     string
+	}{
+		{name: "defaults"},
+		{
+			name:             "global",
+			globalTimezone:   "UTC",
+			globalJitter:     "5m",
+			expectedTimezone: "UTC",
+			expectedJitter:   5 * time.Minute,
+		},
+		{
+			name:             "instance overrides global",
+			globalTimezone:   "UTC",
+			globalJitter:     "5m",
+			timezone:         "Europe/Berlin",
+			jitter:           "30s",
+			expectedTimezone: "Europe/Berlin",
+			expectedJitter:   30 * time.Second,
+		},
+		{
+			name:         "invalid timezone",
+			timezone:     "Mars/Olympus",
+			errorMessage: "invalid schedule",
+		},
+		{
+			name:         "invalid jitter",
+			jitter:       "-1m",
+			errorMessage: "invalid jitter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Timezone: tt.globalTimezone,
+				Jitter:   tt.globalJitter,
+				Instances: []config.BackupInstance{
+					{ID: "test", Schedule: "@daily", Timezone: tt.timezone, Jitter: tt.jitter, Targets: []config.TargetConfig{{Volume: "data"}}},
+				},
+			}
+			schedules, err := BuildSchedulesFromConfig(cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedules[0].Timezone; got != tt.expectedTimezone {
+				t.Errorf("expected timezone %q, got %q", tt.expectedTimezone, got)
+			}
+			if got := schedules[0].Jitter; got != tt.expectedJitter {
+				t.Errorf("expected jitter %v, got %v", tt.expectedJitter, got)
+			}
+		})
+	}
+}
+
+func TestBuildSchedulesFromConfig_Blackouts(t *testing.T) {
+	tests := []struct {
+		name         string
+		global       []config.BlackoutConfig
+		instance     []config.BlackoutConfig
+		expected     []model.BlackoutWindow
+		errorMessage string
+	}{
+		{name: "none"},
+		{
+			name:   "global and instance windows",
+			global: []config.BlackoutConfig{{Start: "22:00", End: "02:00"}},
+			instance: []config.BlackoutConfig{
+				{Days: []string{"Tue", "thursday"}, Start: "09:00", End: "12:30", Policy: "skip"},
+				{Cron: "0 18 * * 5", Duration: "3h"},
+			},
+			expected: []model.BlackoutWindow{
+				{Start: 22 * time.Hour, End: 2 * time.Hour, Timezone: "UTC", Policy: model.BlackoutDefer},
+				{Days: []time.Weekday{time.Tuesday, time.Thursday}, Start: 9 * time.Hour, End: 12*time.Hour + 30*time.Minute, Timezone: "UTC", Policy: model.BlackoutSkip},
+				{Cron: "0 18 * * 5", Duration: 3 * time.Hour, Timezone: "UTC", Policy: model.BlackoutDefer},
+			},
+		},
+		{
+			name:         "mixed window kinds",
+			instance:     []config.BlackoutConfig{{Cron: "0 18 * * 5", Duration: "3h", Start: "09:00"}},
+			errorMessage: "cannot combine",
+		},
+		{
+			name:         "empty window",
+			instance:     []config.BlackoutConfig{{Policy: "skip"}},
+			errorMessage: "must specify either",
+		},
+		{
+			name:         "invalid policy",
+			instance:     []config.BlackoutConfig{{Start: "09:00", End: "10:00", Policy: "ignore"}},
+			errorMessage: "invalid policy",
+		},
+		{
+			name:         "invalid time",
+			instance:     []config.BlackoutConfig{{Start: "25:00", End: "10:00"}},
+			errorMessage: "invalid start",
+		},
+		{
+			name:         "invalid day",
+			instance:     []config.BlackoutConfig{{Days: []string{"mondays"}, Start: "09:00", End: "10:00"}},
+			errorMessage: "invalid day",
+		},
+		{
+			name:         "missing duration",
+			instance:     []config.BlackoutConfig{{Cron: "0 18 * * 5"}},
+			errorMessage: "invalid duration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Timezone:  "UTC",
+				Blackouts: tt.global,
+				Instances: []config.BackupInstance{
+					{ID: "test", Schedule: "0 2 * * *", Blackouts: tt.instance, Targets: []config.TargetConfig{{Volume: "data"}}},
+				},
+			}
+			schedules, err := BuildSchedulesFromConfig(cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedules[0].Blackouts; !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected blackouts %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildSchedulesFromConfig_After(t *testing.T) {
+	cfg := &config.Config{
+		Instances: []config.BackupInstance{
+			{ID: "export", Schedule: "0 2 * * *", Targets: []config.TargetConfig{{Volume: "app"}}},
+			{ID: "upload", After: []string{"export"}, Targets: []config.TargetConfig{{Volume: "exports"}, {DB: "postgres"}}},
+		},
+	}
+
+	schedules, err := BuildSchedulesFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(schedules) != 2 {
+		t.Fatalf("expected 2 schedules, got %d", len(schedules))
+	}
+
+	chained := schedules[1]
+	if chained.ScheduleCron != "" || len(chained.Targets) != 2 {
+		t.Errorf("expected one schedule without cron covering all targets, got %+v", chained)
+	}
+	if len(chained.After) != 1 || chained.After[0] != "export" {
+		t.Errorf("expected after [export], got %v", chained.After)
+	}
+	if got := chained.Describe(); got != "after export" {
+		t.Errorf("expected description %q, got %q", "after export", got)
+	}
+	if got := schedules[0].Describe(); got != "0 2 * * *" {
+		t.Errorf("expected description %q, got %q", "0 2 * * *", got)
+	}
+}
+
+func TestBuildSchedulesFromConfig_Timeouts(t *testing.T) {
+	tests := []struct {
+		name         string
+		global       config.Config
+		instance     config.BackupInstance
+		expected     model.Timeouts
+		errorMessage string
+	}{
+		{
+			name:     "defaults",
+			expected: model.Timeouts{Job: DefaultJobTimeout},
+		},
+		{
+			name:     "global",
+			global:   config.Config{JobTimeout: "2h", HookTimeout: "1m", DumpTimeout: "30m", StagingTimeout: "45m"},
+			expected: model.Timeouts{Job: 2 * time.Hour, Hook: time.Minute, Dump: 30 * time.Minute, Staging: 45 * time.Minute},
+		},
+		{
+			name:     "instance overrides global",
+			global:   config.Config{JobTimeout: "2h", HookTimeout: "1m"},
+			instance: config.BackupInstance{JobTimeout: "6h", DumpTimeout: "10m"},
+			expected: model.Timeouts{Job: 6 * time.Hour, Hook: time.Minute, Dump: 10 * time.Minute},
+		},
+		{
+			name:     "zero disables the job timeout",
+			instance: config.BackupInstance{JobTimeout: "0"},
+			expected: model.Timeouts{},
+		},
+		{
+			name:         "invalid",
+			instance:     config.BackupInstance{HookTimeout: "soon"},
+			errorMessage: "invalid hookTimeout",
+		},
+		{
+			name:         "negative",
+			global:       config.Config{StagingTimeout: "-5m"},
+			errorMessage: "invalid stagingTimeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.global
+			inst := tt.instance
+			inst.ID = "test"
+			inst.Schedule = "@daily"
+			inst.Targets = []config.TargetConfig{{Volume: "data"}}
+			cfg.Instances = []config.BackupInstance{inst}
+
+			schedules, err := BuildSchedulesFromConfig(&cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedules[0].Timeouts; got != tt.expected {
+				t.Errorf("expected timeouts %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
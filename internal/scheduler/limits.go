@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/config"
+)
+
+// BuildConcurrencyLimits validates the job concurrency limits of the config and returns the
+// global limit and the limits per backend type (0 = unlimited)
+func BuildConcurrencyLimits(cfg *config.Config) (int, map[backend.BackendType]int, error) {
+	if cfg.MaxConcurrentJobs < 0 {
+		return 0, nil, fmt.Errorf("maxConcurrentJobs must not be negative, got %d", cfg.MaxConcurrentJobs)
+	}
+
+	perBackend := make(map[backend.BackendType]int, len(cfg.MaxConcurrentJobsPerBackend))
+	for name, limit := range cfg.MaxConcurrentJobsPerBackend {
+		backendType := backend.BackendType(name)
+		if backendType != backend.BackendTypeRestic && backendType != backend.BackendTypeCustomImage {
+			return 0, nil, fmt.Errorf("maxConcurrentJobsPerBackend: unknown backend type %q (must be %s or %s)", name, backend.BackendTypeRestic, backend.BackendTypeCustomImage)
+		}
+		if limit < 0 {
+			return 0, nil, fmt.Errorf("maxConcurrentJobsPerBackend: limit for %s must not be negative, got %d", name, limit)
+		}
+		perBackend[backendType] = limit
+	}
+
+	return cfg.MaxConcurrentJobs, perBackend, nil
+}
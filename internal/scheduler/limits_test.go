@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/polarfoxDev/marina/internal/backend"
+	"github.com/polarfoxDev/marina/internal/config"
+)
+
+func TestBuildConcurrencyLimits(t *testing.T) {
+	tests := []struct {
+		name               string
+		maxJobs            int
+		perBackend         map[string]int
+		expectedMax        int
+		expectedPerBackend map[backend.BackendType]int
+		errorMessage       string
+	}{
+		{
+			name:               "unlimited by default",
+			expectedPerBackend: map[backend.BackendType]int{},
+		},
+		{
+			name:               "global and per backend",
+			maxJobs:            3,
+			perBackend:         map[string]int{"restic": 2, "custom": 1},
+			expectedMax:        3,
+			expectedPerBackend: map[backend.BackendType]int{backend.BackendTypeRestic: 2, backend.BackendTypeCustomImage: 1},
+		},
+		{
+			name:         "negative global limit",
+			maxJobs:      -1,
+			errorMessage: "maxConcurrentJobs must not be negative",
+		},
+		{
+			name:         "unknown backend type",
+			perBackend:   map[string]int{"rsync": 1},
+			errorMessage: "unknown backend type",
+		},
+		{
+			name:         "negative backend limit",
+			perBackend:   map[string]int{"restic": -2},
+			errorMessage: "must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{MaxConcurrentJobs: tt.maxJobs, MaxConcurrentJobsPerBackend: tt.perBackend}
+			maxJobs, perBackend, err := BuildConcurrencyLimits(cfg)
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if maxJobs != tt.expectedMax {
+				t.Errorf("expected max %d, got %d", tt.expectedMax, maxJobs)
+			}
+			if !maps.Equal(perBackend, tt.expectedPerBackend) {
+				t.Errorf("expected per backend limits %v, got %v", tt.expectedPerBackend, perBackend)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/config"
+	"github.com/polarfoxDev/marina/internal/model"
+)
+
+// DefaultJobTimeout is how long a run may take if jobTimeout is not set
+const DefaultJobTimeout = 12 * time.Hour
+
+// DefaultShutdownTimeout is how long running jobs may finish on shutdown if shutdownTimeout is not set
+const DefaultShutdownTimeout = 5 * time.Minute
+
+// BuildShutdownTimeout validates shutdownTimeout of the config and returns how long running jobs
+// may finish on shutdown before they are aborted (0 = abort them right away)
+func BuildShutdownTimeout(cfg *config.Config) (time.Duration, error) {
+	if cfg.ShutdownTimeout == "" {
+		return DefaultShutdownTimeout, nil
+	}
+	timeout, err := time.ParseDuration(cfg.ShutdownTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shutdownTimeout %q: %w", cfg.ShutdownTimeout, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("shutdownTimeout must not be negative, got %s", cfg.ShutdownTimeout)
+	}
+	return timeout, nil
+}
+
+// resolveTimeouts determines the job, hook, dump and staging timeouts of an instance (instance > global).
+// The job timeout defaults to DefaultJobTimeout, the others to no limit; "0" disables a timeout.
+func resolveTimeouts(cfg *config.Config, inst config.BackupInstance) (model.Timeouts, error) {
+	timeouts := model.Timeouts{Job: DefaultJobTimeout}
+	for _, setting := range []struct {
+		name             string
+		global, instance string
+		timeout          *time.Duration
+	}{
+		{"jobTimeout", cfg.JobTimeout, inst.JobTimeout, &timeouts.Job},
+		{"hookTimeout", cfg.HookTimeout, inst.HookTimeout, &timeouts.Hook},
+		{"dumpTimeout", cfg.DumpTimeout, inst.DumpTimeout, &timeouts.Dump},
+		{"stagingTimeout", cfg.StagingTimeout, inst.StagingTimeout, &timeouts.Staging},
+	} {
+		value := setting.instance
+		if value == "" {
+			value = setting.global
+		}
+		if value == "" {
+			continue
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return timeouts, fmt.Errorf("invalid %s %q (must be a duration like 30m)", setting.name, value)
+		}
+		*setting.timeout = timeout
+	}
+	return timeouts, nil
+}
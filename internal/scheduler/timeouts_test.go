@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/polarfoxDev/marina/internal/config"
+)
+
+func TestBuildShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		expected     time.Duration
+		errorMessage string
+	}{
+		{
+			name:     "default",
+			expected: DefaultShutdownTimeout,
+		},
+		{
+			name:     "custom",
+			value:    "90s",
+			expected: 90 * time.Second,
+		},
+		{
+			name:     "abort right away",
+			value:    "0s",
+			expected: 0,
+		},
+		{
+			name:         "invalid",
+			value:        "soon",
+			errorMessage: "invalid shutdownTimeout",
+		},
+		{
+			name:         "negative",
+			value:        "-1m",
+			errorMessage: "must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout, err := BuildShutdownTimeout(&config.Config{ShutdownTimeout: tt.value})
+			if tt.errorMessage != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if timeout != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, timeout)
+			}
+		})
+	}
+}
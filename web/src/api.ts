@@ -1,8 +1,11 @@
 import type {
+  CancelJobResponse,
+  ControlCommand,
   HealthResponse,
   InstanceBackupSchedule,
   JobStatus,
   LogEntry,
+  RunInstanceResponse,
   SystemLogEntry,
 } from "./types";
 
@@ -97,6 +100,32 @@ export const api = {
     return fetchJson<LogEntry[]>(url);
   },
 
+  async runInstance(
+    instanceID: string,
+    targetIds: string[] = []
+  ): Promise<RunInstanceResponse> {
+    return postJson<RunInstanceResponse>(
+      `${API_BASE}/instances/${encodeURIComponent(instanceID)}/run`,
+      { targetIds }
+    );
+  },
+
+  async cancelJob(jobID: number, nodeUrl?: string): Promise<CancelJobResponse> {
+    let url = `${API_BASE}/jobs/${jobID}/cancel`;
+    if (nodeUrl) {
+      url += `?nodeUrl=${encodeURIComponent(nodeUrl)}`;
+    }
+    return postJson<CancelJobResponse>(url, {});
+  },
+
+  async getCommand(commandID: number, nodeUrl?: string): Promise<ControlCommand> {
+    let url = `${API_BASE}/commands/${commandID}`;
+    if (nodeUrl) {
+      url += `?nodeUrl=${encodeURIComponent(nodeUrl)}`;
+    }
+    return fetchJson<ControlCommand>(url);
+  },
+
   async getSystemLogs(
     limit = 1000,
     level?: string
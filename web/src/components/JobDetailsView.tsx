@@ -22,6 +22,8 @@ export function JobDetailsView() {
   const [filteredLogs, setFilteredLogs] = useState<LogEntry[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
+  const [cancelling, setCancelling] = useState(false);
+  const [cancelError, setCancelError] = useState<string | null>(null);
 
   // Filters
   const [targetFilter, setTargetFilter] = useState<string>("all");
@@ -92,12 +94,43 @@ export function JobDetailsView() {
     loadLogs(job?.nodeUrl || undefined);
   }, [jobId, job?.nodeUrl, loadLogs]);
 
+  const cancelJob = async () => {
+    if (!job) return;
+    if (!window.confirm(`Cancel job #${job.iid}?`)) return;
+
+    setCancelling(true);
+    setCancelError(null);
+    try {
+      const nodeUrl = job.nodeUrl || undefined;
+      const response = await api.cancelJob(job.id, nodeUrl);
+      // The manager has not processed the request yet: poll the command for its outcome
+      if (response.status === "pending") {
+        let command = await api.getCommand(response.commandId, nodeUrl);
+        for (let i = 0; !command.processedAt && i < 10; i++) {
+          await new Promise((resolve) => setTimeout(resolve, 2000));
+          command = await api.getCommand(response.commandId, nodeUrl);
+        }
+        if (command.processedAt && command.result !== "ok") {
+          throw new Error(`Failed to cancel job: ${command.result}`);
+        }
+      }
+      loadJobStatus();
+    } catch (err) {
+      setCancelError(
+        err instanceof Error ? err.message : "Failed to cancel job"
+      );
+    } finally {
+      setCancelling(false);
+    }
+  };
+
   // Track previous status to detect transitions
   const [prevStatus, setPrevStatus] = useState<JobStatusState | null>(null);
 
-  // Poll job status every 5 seconds when job is in progress
+  // Poll job status every 5 seconds when job is in progress or waiting in the queue
   useEffect(() => {
-    if (!jobId || job?.status !== "in_progress") return;
+    if (!jobId || (job?.status !== "in_progress" && job?.status !== "queued"))
+      return;
 
     const statusInterval = setInterval(() => {
       loadJobStatus();
@@ -182,7 +215,18 @@ export function JobDetailsView() {
 
       {/* Job Status Card */}
       <div className="bg-white shadow rounded-lg p-6 mb-6">
-        <h2 className="text-xl font-semibold text-gray-900 mb-4">Job Status</h2>
+        <div className="flex items-center justify-between mb-4">
+          <h2 className="text-xl font-semibold text-gray-900">Job Status</h2>
+          {(job.status === "in_progress" || job.status === "queued") && (
+            <button
+              onClick={cancelJob}
+              disabled={cancelling}
+              className="px-3 py-1 text-sm font-medium text-red-700 bg-red-100 rounded-md hover:bg-red-200 disabled:opacity-50"
+            >
+              {cancelling ? "Cancelling..." : "Cancel Job"}
+            </button>
+          )}
+        </div>
         <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-4">
           <div>
             <div className="text-sm text-gray-500">Status</div>
@@ -213,6 +257,17 @@ export function JobDetailsView() {
             </div>
           </div>
         </div>
+        {job.attempts > 1 && (
+          <div className="mt-4 text-sm text-gray-700">
+            Needed {job.attempts} attempts (retries with backoff)
+          </div>
+        )}
+        {job.message && (
+          <div className="mt-4 text-sm text-gray-700">{job.message}</div>
+        )}
+        {cancelError && (
+          <div className="mt-4 text-sm text-red-600">{cancelError}</div>
+        )}
       </div>
 
       {/* Filters */}
@@ -14,6 +14,8 @@ export function JobStatusesView() {
   const [jobs, setJobs] = useState<JobStatus[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
+  const [starting, setStarting] = useState(false);
+  const [runMessage, setRunMessage] = useState<string | null>(null);
 
   const loadJobs = useCallback(async () => {
     if (!instanceId) return;
@@ -31,6 +33,28 @@ export function JobStatusesView() {
     }
   }, [instanceId]);
 
+  const runNow = async () => {
+    if (!instanceId) return;
+
+    setStarting(true);
+    setRunMessage(null);
+    try {
+      const result = await api.runInstance(instanceId);
+      setRunMessage(
+        result.jobId
+          ? `Started job (status: ${result.status})`
+          : "Run requested; it will start shortly"
+      );
+      await loadJobs();
+    } catch (err) {
+      setRunMessage(
+        err instanceof Error ? err.message : "Failed to start backup"
+      );
+    } finally {
+      setStarting(false);
+    }
+  };
+
   useEffect(() => {
     if (instanceId) {
       loadJobs();
@@ -64,12 +88,24 @@ export function JobStatusesView() {
         >
           ← Back to Schedules
         </Link>
-        <h1 className="text-3xl font-bold text-gray-900">
-          Backup Jobs: {instanceId}
-        </h1>
+        <div className="flex items-center justify-between">
+          <h1 className="text-3xl font-bold text-gray-900">
+            Backup Jobs: {instanceId}
+          </h1>
+          <button
+            onClick={runNow}
+            disabled={starting}
+            className="px-4 py-2 text-sm font-medium text-white bg-blue-600 rounded-md hover:bg-blue-700 disabled:opacity-50"
+          >
+            {starting ? "Starting..." : "Run Now"}
+          </button>
+        </div>
         <p className="mt-2 text-gray-600">
           History of backup jobs for this instance
         </p>
+        {runMessage && (
+          <p className="mt-1 text-sm text-gray-700">{runMessage}</p>
+        )}
         {jobs.length > 0 && (() => {
           const nodes = [...new Set(jobs.map(j => j.nodeName).filter(Boolean))];
           if (nodes.length > 1) {
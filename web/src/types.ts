@@ -4,7 +4,10 @@ export type JobStatusState =
   | "partial_success"
   | "failed"
   | "scheduled"
-  | "aborted";
+  | "queued"
+  | "skipped"
+  | "aborted"
+  | "cancelled";
 
 export interface Retention {
   keepDaily: number;
@@ -37,6 +40,9 @@ export interface JobStatus {
   lastCompletedAt: string | null;
   lastTargetsSuccessful: number;
   lastTargetsTotal: number;
+  message?: string; // Reason for skipped, aborted or cancelled runs
+  attempts: number; // Highest attempt number any staging or upload step needed
+  targetIds?: string[]; // Targets covered by this run
   createdAt: string;
   updatedAt: string;
 }
@@ -66,3 +72,26 @@ export interface HealthResponse {
   status: string;
   time: string;
 }
+
+export interface CancelJobResponse {
+  commandId: number;
+  jobId: number;
+  status: "cancelling" | "pending"; // pending until the manager has processed the request
+}
+
+export interface ControlCommand {
+  id: number;
+  type: "cancel" | "run" | "dry_run";
+  jobStatusId?: number;
+  instanceId?: string;
+  result?: string; // "ok" or the reason the manager rejected the command
+  createdAt: string;
+  processedAt: string | null; // null while pending
+}
+
+export interface RunInstanceResponse {
+  commandId: number;
+  instanceId: string;
+  jobId?: number; // set once the manager has started the run
+  status: JobStatusState | "pending";
+}
@@ -43,8 +43,11 @@ export function getStatusColor(status: JobStatusState): string {
     case "in_progress":
       return "text-blue-700 bg-blue-100";
     case "scheduled":
+    case "queued":
+    case "skipped":
       return "text-gray-700 bg-gray-100";
     case "aborted":
+    case "cancelled":
       return "text-orange-700 bg-orange-100";
     default:
       return "text-gray-700 bg-gray-100";